@@ -0,0 +1,83 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command govice-check is a preflight check of the configuration of a service. It loads the configuration file and
+// the environment variables into the configuration struct (rebuilt from the source code of the package) and
+// validates it against the JSON schema. Every problem is written to the standard error and the command exits with
+// status 1, so it can be run by the entrypoint of a container or by a CI job before starting the service.
+//
+// The methods of the types are lost when the struct is rebuilt, so the command refuses the structs with types
+// implementing UnmarshalJSON or UnmarshalText. For these services (or to check exactly what the service loads),
+// call govice.CheckConfig from the binary of the service instead (e.g. with a -check flag).
+//
+// Usage:
+//
+//	govice-check -dir ./ -type config -config config.json -schemas schemas
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/Telefonica/govice"
+	"github.com/Telefonica/govice/internal/gostruct"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the package with the configuration struct")
+	typeName := flag.String("type", "", "name of the configuration struct")
+	configFile := flag.String("config", "", "default configuration file")
+	schemasDir := flag.String("schemas", "", "directory of the JSON schemas (validation is skipped if empty)")
+	schemaName := flag.String("schema", "config", "name of the JSON schema of the configuration")
+	envPrefix := flag.String("env-prefix", "", "prefix of the environment variables derived from the fields")
+	dotEnvFile := flag.String("dotenv", "", ".env file with environment variables")
+	strict := flag.Bool("strict", false, "report the unknown keys of the configuration file")
+	flag.Parse()
+	if *typeName == "" || *configFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	t, err := gostruct.Load(*dir, *typeName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	loader := govice.NewConfigLoader(*configFile)
+	if *envPrefix != "" {
+		loader.SetEnvPrefix(*envPrefix)
+	}
+	if *dotEnvFile != "" {
+		loader.SetDotEnvFile(*dotEnvFile)
+	}
+	if *strict {
+		loader.SetStrictMode(govice.StrictModeError)
+	}
+	err = loader.Check(reflect.New(t).Interface(), *schemasDir, *schemaName)
+	if checkErr, ok := err.(*govice.ConfigCheckError); ok {
+		for _, problem := range checkErr.Problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println("Configuration OK")
+}
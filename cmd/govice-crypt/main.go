@@ -0,0 +1,95 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command govice-crypt encrypts and decrypts configuration values (see govice.EncryptConfigValue).
+// The key is read from the file set with -key or from the environment variables GOVICE_CONFIG_KEY
+// or GOVICE_CONFIG_KEY_FILE. The value to encrypt is read from the standard input, so it is not visible in the
+// list of processes or in the shell history.
+//
+// Usage:
+//
+//	govice-crypt genkey > config.key
+//	echo -n s3cr3t | govice-crypt -key config.key encrypt
+//	govice-crypt -key config.key decrypt enc:AES256GCM:...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/Telefonica/govice"
+)
+
+func main() {
+	keyFile := flag.String("key", "", "file with the key (base64 encoded)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-key file] genkey | encrypt [-] | decrypt VALUE|-\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "The value to encrypt (and the value to decrypt with -) is read from the standard input.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 1 && args[0] == "genkey" {
+		key, err := govice.GenerateConfigKey()
+		exitOnError(err)
+		fmt.Println(key)
+		return
+	}
+	encrypt := len(args) >= 1 && args[0] == "encrypt" && (len(args) == 1 || (len(args) == 2 && args[1] == "-"))
+	decrypt := len(args) == 2 && args[0] == "decrypt"
+	if !encrypt && !decrypt {
+		flag.Usage()
+		os.Exit(2)
+	}
+	key, err := govice.ReadConfigKey(*keyFile, os.LookupEnv)
+	exitOnError(err)
+	input := ""
+	if encrypt || args[1] == "-" {
+		input, err = readStdin()
+		exitOnError(err)
+	} else {
+		input = args[1]
+	}
+	var value string
+	if encrypt {
+		value, err = govice.EncryptConfigValue(key, input)
+	} else {
+		value, err = govice.DecryptConfigValue(key, strings.TrimSpace(input))
+	}
+	exitOnError(err)
+	fmt.Println(value)
+}
+
+// readStdin reads the value from the standard input without the trailing line break (e.g. added by echo).
+func readStdin() (string, error) {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	s := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(s, "\r"), nil
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,73 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command govice-doc documents the configuration of a service (fields, environment variables, types, default
+// values and descriptions) from the source code of its configuration struct and its default configuration file.
+//
+// Usage:
+//
+//	govice-doc -dir ./ -type config -config config.json -env-prefix USERS_ -format markdown
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/Telefonica/govice"
+	"github.com/Telefonica/govice/internal/gostruct"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the package with the configuration struct")
+	typeName := flag.String("type", "", "name of the configuration struct")
+	configFile := flag.String("config", "", "default configuration file")
+	envPrefix := flag.String("env-prefix", "", "prefix of the environment variables derived from the fields")
+	format := flag.String("format", "text", "output format: text or markdown")
+	flag.Parse()
+	if *typeName == "" || (*format != "text" && *format != "markdown") {
+		flag.Usage()
+		os.Exit(2)
+	}
+	t, err := gostruct.Load(*dir, *typeName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	loader := &govice.ConfigLoader{}
+	if *configFile != "" {
+		loader = govice.NewConfigLoader(*configFile)
+	}
+	if *envPrefix != "" {
+		loader.SetEnvPrefix(*envPrefix)
+	}
+	doc, err := loader.GetConfigDoc(reflect.New(t).Interface())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *format == "markdown" {
+		err = doc.WriteMarkdown(os.Stdout)
+	} else {
+		err = doc.WriteTable(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
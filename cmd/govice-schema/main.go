@@ -0,0 +1,64 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command govice-schema generates the JSON schema of a configuration struct from the source code of a package.
+//
+// Usage:
+//
+//	govice-schema -dir ./ -type config -o schemas/config.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+
+	"github.com/Telefonica/govice"
+	"github.com/Telefonica/govice/internal/gostruct"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the package with the configuration struct")
+	typeName := flag.String("type", "", "name of the configuration struct")
+	output := flag.String("o", "", "output file (standard output if empty)")
+	flag.Parse()
+	if *typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	t, err := gostruct.Load(*dir, *typeName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	schema, err := govice.GenerateSchema(reflect.New(t).Interface())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	schema = append(schema, '\n')
+	if *output == "" {
+		os.Stdout.Write(schema)
+		return
+	}
+	if err := ioutil.WriteFile(*output, schema, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -18,13 +18,11 @@
 package govice
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
-
-	"github.com/caarlos0/env"
-	"github.com/imdario/mergo"
+	"strings"
 )
 
 // NewType creates a new object with the same type using reflection.
@@ -37,31 +35,244 @@ func NewType(orig interface{}) interface{} {
 	return reflect.New(val.Type()).Interface()
 }
 
-func loadConfigFile(configFile string, config interface{}) error {
-	bytes, err := ioutil.ReadFile(configFile)
-	if err != nil {
-		return err
+// ProfileEnvVar is the name of the environment variable with the configuration profiles used by GetConfigProfile.
+// It may contain several profiles separated by commas (e.g. "production,local").
+var ProfileEnvVar = "GOVICE_PROFILE"
+
+// configLayer is a configuration file to be merged. Optional layers are skipped if the file does not exist.
+// Remote layers are fetched from the URL in file (see RemoteConfig).
+type configLayer struct {
+	file     string
+	optional bool
+	remote   *RemoteConfig
+}
+
+// Sources of the configuration values.
+const (
+	ConfigSourceDefault    = "default"
+	ConfigSourceFile       = "file"
+	ConfigSourceEnv        = "env"
+	ConfigSourceSecretFile = "secretFile"
+	ConfigSourceFlag       = "flag"
+	ConfigSourceDotEnv     = "dotEnv"
+	ConfigSourceRemote     = "remote"
+)
+
+// configOrigin identifies the source that set a configuration value and its name (e.g. file path or
+// environment variable name).
+type configOrigin struct {
+	source string
+	name   string
+}
+
+// ConfigLoader loads the configuration from an ordered list of files (layers) and environment variables.
+// The layers are deep merged in order (a layer overrides the values of the previous ones) before applying
+// the environment variables.
+type ConfigLoader struct {
+	layers     []configLayer
+	flags      *ConfigFlagSet
+	envPrefix  string
+	autoEnv    bool
+	strictMode StrictMode
+	dotEnvFile string
+	keyFile    string
+	dotEnv     map[string]string
+	logger     *Logger
+	fields     []configField
+	origins    map[string]configOrigin
+}
+
+// NewConfigLoader creates a ConfigLoader with a base configuration file. This file is required.
+func NewConfigLoader(configFile string) *ConfigLoader {
+	return &ConfigLoader{layers: []configLayer{{file: configFile}}}
+}
+
+// AddLayer appends an optional configuration file that overrides the previous layers.
+// If the file does not exist, it is skipped.
+func (c *ConfigLoader) AddLayer(configFile string) {
+	c.layers = append(c.layers, configLayer{file: configFile, optional: true})
+}
+
+// AddProfile appends an optional layer for a profile. The file name is built by inserting the profile
+// before the extension of the base configuration file (e.g. config.production.json for config.json).
+func (c *ConfigLoader) AddProfile(profile string) {
+	c.AddLayer(profileConfigFile(c.layers[0].file, profile))
+}
+
+// AddEnvProfiles appends the layers of the profiles in the environment variable ProfileEnvVar (GOVICE_PROFILE),
+// separated by commas (see AddProfile). The variable may also be set in the .env file (see SetDotEnvFile),
+// so it must be called after setting the .env file.
+func (c *ConfigLoader) AddEnvProfiles() error {
+	if err := c.readDotEnv(); err != nil {
+		return fmt.Errorf("Error processing .env file. %s", err)
 	}
-	return json.Unmarshal(bytes, config)
+	profiles, _ := c.lookupEnv(ProfileEnvVar)
+	for _, profile := range strings.Split(profiles, ",") {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			c.AddProfile(profile)
+		}
+	}
+	return nil
 }
 
-// GetConfig prepares the configuration by merging multiple sources:
-// - Default configuration stored in a json file
-// - Environment variables
-func GetConfig(configFile string, config interface{}) error {
-	// Get the environment variables
-	if err := env.Parse(config); err != nil {
+// GetLayers returns the list of configuration files (or URLs of the remote layers) in merge order.
+func (c *ConfigLoader) GetLayers() []string {
+	files := make([]string, len(c.layers))
+	for i, layer := range c.layers {
+		files[i] = layer.file
+	}
+	return files
+}
+
+// SetFlags sets the command-line flags bound to the configuration (see BindConfigFlags).
+func (c *ConfigLoader) SetFlags(flags *ConfigFlagSet) {
+	c.flags = flags
+}
+
+// Load prepares the configuration by merging the configuration layers, the environment variables and the
+// command-line flags (see SetFlags). The placeholders ${VAR} and ${VAR:-default} in the string values of the
+// configuration files are expanded with the environment variables, and the encrypted values are decrypted
+// (see EncryptConfigValue).
+// The precedence is: flags > environment variables > configuration files > default values in the struct.
+// Note that only the sources that set a field explicitly override it, even with a zero value
+// (e.g. an environment variable set to false or a JSON key set to "").
+func (c *ConfigLoader) Load(config interface{}) error {
+	root := reflect.ValueOf(config)
+	if root.Kind() != reflect.Ptr || root.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Error processing configuration. Expected a pointer to a struct")
+	}
+	root = root.Elem()
+	fields := getConfigFields(root.Type())
+	c.fields = fields
+	c.origins = make(map[string]configOrigin)
+
+	// Get the variables of the .env file
+	if err := c.readDotEnv(); err != nil {
+		return fmt.Errorf("Error processing .env file. %s", err)
+	}
+
+	// Get the default values of the environment variables
+	if err := c.applyEnvDefaults(root, fields); err != nil {
 		return fmt.Errorf("Error processing environment variables. %s", err)
 	}
 
 	// Get the default configuration
-	defaultConfig := NewType(config)
-	if err := loadConfigFile(configFile, defaultConfig); err != nil {
+	doc, err := c.readDocument()
+	if err != nil {
+		return fmt.Errorf("Error processing default configuration. %s", err)
+	}
+	if err := doc.interpolate(c.lookupEnv); err != nil {
 		return fmt.Errorf("Error processing default configuration. %s", err)
 	}
-	if err := mergo.Merge(config, defaultConfig); err != nil {
-		return fmt.Errorf("Error merging the default configuration. %s", err)
+	if err := c.decrypt(doc); err != nil {
+		return fmt.Errorf("Error processing default configuration. %s", err)
+	}
+	if err := c.checkUnknownKeys(doc, root.Type()); err != nil {
+		return fmt.Errorf("Error processing default configuration. %s", err)
+	}
+	if err := doc.unmarshal(config); err != nil {
+		return fmt.Errorf("Error processing default configuration. %s", err)
+	}
+	c.setDocumentOrigins(doc, doc.values, "")
+
+	// Get the environment variables
+	if err := c.applyEnv(root, fields); err != nil {
+		return fmt.Errorf("Error processing environment variables. %s", err)
+	}
+
+	// Get the command-line flags
+	if c.flags != nil {
+		if err := c.applyFlags(root); err != nil {
+			return fmt.Errorf("Error processing command-line flags. %s", err)
+		}
 	}
 
 	return nil
 }
+
+// setDocumentOrigins registers the file that set every value in the document.
+func (c *ConfigLoader) setDocumentOrigins(doc *configDocument, values map[string]interface{}, path string) {
+	for key, value := range values {
+		keyPath := joinConfigPath(path, key)
+		if object, ok := value.(map[string]interface{}); ok && len(object) > 0 {
+			c.setDocumentOrigins(doc, object, keyPath)
+			continue
+		}
+		origin := configOrigin{source: ConfigSourceFile, name: doc.keyFile(keyPath)}
+		if c.isRemoteLayer(origin.name) {
+			origin.source = ConfigSourceRemote
+		}
+		c.origins[keyPath] = origin
+	}
+}
+
+// readDocument reads and merges all the configuration layers.
+func (c *ConfigLoader) readDocument() (*configDocument, error) {
+	var doc *configDocument
+	for _, layer := range c.layers {
+		layerDoc, err := c.readLayer(layer)
+		if err != nil {
+			if layer.optional && os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if doc == nil {
+			doc = layerDoc
+		} else {
+			doc.merge(layerDoc)
+		}
+	}
+	return doc, nil
+}
+
+// readLayer reads a configuration layer. If a remote layer is not available, its cached copy is used
+// (logging a warning).
+func (c *ConfigLoader) readLayer(layer configLayer) (*configDocument, error) {
+	if layer.remote == nil {
+		return readConfigDocument(layer.file)
+	}
+	doc, warning, err := layer.remote.read()
+	if warning != nil {
+		c.getLogger().Warn("Error reading remote configuration. %s", warning)
+	}
+	return doc, err
+}
+
+func profileConfigFile(configFile, profile string) string {
+	ext := filepath.Ext(configFile)
+	return strings.TrimSuffix(configFile, ext) + "." + profile + ext
+}
+
+// GetConfig prepares the configuration by merging multiple sources:
+//   - Default configuration stored in a file. The format depends on the file extension:
+//     JSON (.json), YAML (.yaml or .yml) or TOML (.toml). Other extensions are read as JSON.
+//   - Environment variables
+func GetConfig(configFile string, config interface{}) error {
+	return NewConfigLoader(configFile).Load(config)
+}
+
+// GetConfigLayers works like GetConfig but the default configuration is the result of deep merging a list of
+// configuration files in order. The first file is required and the rest are optional (skipped if they do not exist).
+func GetConfigLayers(configFiles []string, config interface{}) error {
+	if len(configFiles) == 0 {
+		return fmt.Errorf("Error processing default configuration. No configuration file")
+	}
+	loader := NewConfigLoader(configFiles[0])
+	for _, configFile := range configFiles[1:] {
+		loader.AddLayer(configFile)
+	}
+	return loader.Load(config)
+}
+
+// GetConfigProfile works like GetConfig but the base configuration file is overridden by the optional layers
+// of the profiles in the environment variable ProfileEnvVar (GOVICE_PROFILE).
+// For example, with GOVICE_PROFILE=production,local, the configuration is the result of merging
+// config.json, config.production.json and config.local.json.
+func GetConfigProfile(configFile string, config interface{}) error {
+	loader := NewConfigLoader(configFile)
+	if err := loader.AddEnvProfiles(); err != nil {
+		return err
+	}
+	return loader.Load(config)
+}
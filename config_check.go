@@ -0,0 +1,132 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// ConfigCheckError is an error with every problem found by a preflight check of the configuration
+// (see CheckConfig).
+type ConfigCheckError struct {
+	Problems []string
+}
+
+func (e *ConfigCheckError) Error() string {
+	return "Invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Check is a preflight check of the configuration, e.g. to be run by the entrypoint of a container or by a CI job
+// before starting the service. It loads the configuration (see Load) and validates it against the JSON schema
+// schemaName stored in the schemasDir directory (skipped if schemasDir is empty).
+// Unlike LoadValid, it does not stop at the first problem: it returns a *ConfigCheckError with every unreadable
+// configuration file, environment variable error (e.g. a required variable that is not set), invalid JSON schema
+// and violation of the JSON schema.
+func (c *ConfigLoader) Check(config interface{}, schemasDir, schemaName string) error {
+	root := reflect.ValueOf(config)
+	if root.Kind() != reflect.Ptr || root.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Error processing configuration. Expected a pointer to a struct")
+	}
+	var problems []string
+	var validator *Validator
+	if schemasDir != "" {
+		validator = NewValidator()
+		if err := validator.LoadSchemas(schemasDir); err != nil {
+			problems = append(problems, err.Error())
+			validator = nil
+		}
+	}
+	if layerProblems := c.checkLayers(); len(layerProblems) > 0 {
+		problems = append(problems, layerProblems...)
+		// The configuration cannot be loaded but the environment variables are still checked
+		if err := c.checkEnv(root.Elem().Type()); err != nil {
+			problems = append(problems, err.Error())
+		}
+	} else if err := c.Load(config); err != nil {
+		problems = append(problems, err.Error())
+		// Load stops at the first error, so the environment variables may not have been checked
+		if envErr := c.checkEnv(root.Elem().Type()); envErr != nil && envErr.Error() != err.Error() {
+			problems = append(problems, envErr.Error())
+		}
+	} else if validator != nil {
+		problems = append(problems, c.checkSchema(config, validator, schemaName)...)
+	}
+	if len(problems) > 0 {
+		return &ConfigCheckError{Problems: problems}
+	}
+	return nil
+}
+
+// checkLayers returns a problem for every configuration file that cannot be read. Optional layers that do not
+// exist and remote layers are skipped.
+func (c *ConfigLoader) checkLayers() []string {
+	var problems []string
+	for _, layer := range c.layers {
+		if layer.remote != nil {
+			continue
+		}
+		if _, err := ioutil.ReadFile(layer.file); err != nil {
+			if layer.optional && os.IsNotExist(err) {
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("Error processing default configuration. %s", err))
+		}
+	}
+	return problems
+}
+
+// checkEnv applies the environment variables to an empty configuration of type t to report their errors
+// without loading the configuration files.
+func (c *ConfigLoader) checkEnv(t reflect.Type) error {
+	c.origins = make(map[string]configOrigin)
+	if err := c.readDotEnv(); err != nil {
+		return fmt.Errorf("Error processing .env file. %s", err)
+	}
+	root := reflect.New(t).Elem()
+	if err := c.applyEnv(root, getConfigFields(t)); err != nil {
+		return fmt.Errorf("Error processing environment variables. %s", err)
+	}
+	return nil
+}
+
+// checkSchema returns a problem for every violation of the JSON schema by the configuration.
+func (c *ConfigLoader) checkSchema(config interface{}, validator *Validator, schemaName string) []string {
+	err := c.validate(config, validator, schemaName)
+	if err == nil {
+		return nil
+	}
+	validationErr, ok := err.(*ConfigValidationError)
+	if !ok {
+		return []string{err.Error()}
+	}
+	problems := make([]string, len(validationErr.Violations))
+	for i, violation := range validationErr.Violations {
+		problems[i] = "Invalid configuration according to JSON schema: " + violation.String()
+	}
+	return problems
+}
+
+// CheckConfig is a preflight check of the configuration file and the environment variables (see GetConfig) that
+// reports every problem instead of the first one (see ConfigLoader.Check).
+func CheckConfig(configFile, schemasDir, schemaName string, config interface{}) error {
+	return NewConfigLoader(configFile).Check(config, schemasDir, schemaName)
+}
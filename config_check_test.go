@@ -0,0 +1,119 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type checkConfig struct {
+	Address  string `json:"address" env:"ADDRESS"`
+	BasePath string `json:"basePath" env:"BASE_PATH"`
+	LogLevel string `json:"logLevel" env:"LOG_LEVEL"`
+	Realm    string `json:"realm" env:"REALM"`
+	Token    string `json:"-" env:"CHECK_TOKEN,required"`
+}
+
+func TestCheckConfig(t *testing.T) {
+	os.Unsetenv("ADDRESS")
+	os.Unsetenv("LOG_LEVEL")
+	os.Setenv("CHECK_TOKEN", "t0k3n")
+	defer os.Unsetenv("CHECK_TOKEN")
+	var cfg checkConfig
+	if err := CheckConfig("testdata/config.json", "testdata/schemas", "config", &cfg); err != nil {
+		t.Fatalf("Unexpected error checking config. %s", err)
+	}
+	if cfg.Realm != "es" || cfg.Token != "t0k3n" {
+		t.Errorf("Invalid config. Actual: %+v", cfg)
+	}
+}
+
+func TestCheckConfigProblems(t *testing.T) {
+	file := writeTestFile(t, "config.json", `{"address": "localhost", "basePath": "/users", "logLevel": "INFO", "realm": "xx"}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	os.Unsetenv("ADDRESS")
+	os.Unsetenv("LOG_LEVEL")
+	os.Setenv("CHECK_TOKEN", "t0k3n")
+	defer os.Unsetenv("CHECK_TOKEN")
+	var cfg checkConfig
+	err := CheckConfig(file, "testdata/schemas", "config", &cfg)
+	checkErr, ok := err.(*ConfigCheckError)
+	if !ok {
+		t.Fatalf("Invalid error type. Actual: %v", err)
+	}
+	expected := []string{
+		`Invalid configuration according to JSON schema: address: Does not match pattern '^(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3})?:\d{2,4}$' (file:` + file + `)`,
+		`Invalid configuration according to JSON schema: realm: realm must be one of the following: "es", "tu", "uk", "br", "ar", "pe" (file:` + file + `)`,
+	}
+	if !reflect.DeepEqual(checkErr.Problems, expected) {
+		t.Errorf("Invalid problems. Actual: %q. Expected: %q", checkErr.Problems, expected)
+	}
+}
+
+func TestCheckConfigInvalidFile(t *testing.T) {
+	file := writeTestFile(t, "config.json", `{"address": `)
+	defer os.RemoveAll(filepath.Dir(file))
+	os.Unsetenv("CHECK_TOKEN")
+	var cfg checkConfig
+	err := CheckConfig(file, "", "config", &cfg)
+	checkErr, ok := err.(*ConfigCheckError)
+	if !ok {
+		t.Fatalf("Invalid error type. Actual: %v", err)
+	}
+	expected := []string{
+		"Error processing default configuration. ",
+		`Error processing environment variables. required environment variable "CHECK_TOKEN" is not set`,
+	}
+	if len(checkErr.Problems) != len(expected) {
+		t.Fatalf("Invalid problems. Actual: %q. Expected: %q", checkErr.Problems, expected)
+	}
+	for i, problem := range checkErr.Problems {
+		if !strings.HasPrefix(problem, expected[i]) {
+			t.Errorf("Invalid problem. Actual: %q. Expected prefix: %q", problem, expected[i])
+		}
+	}
+}
+
+func TestCheckConfigUnreadable(t *testing.T) {
+	os.Unsetenv("CHECK_TOKEN")
+	var cfg checkConfig
+	loader := NewConfigLoader("testdata/configNotExistent.json")
+	loader.AddLayer("testdata/config.local.json")
+	err := loader.Check(&cfg, "testdata/schemasNotExistent", "config")
+	checkErr, ok := err.(*ConfigCheckError)
+	if !ok {
+		t.Fatalf("Invalid error type. Actual: %v", err)
+	}
+	expected := []string{
+		"Error reading schemas directory: ",
+		"Error processing default configuration. open testdata/configNotExistent.json: no such file or directory",
+		`Error processing environment variables. required environment variable "CHECK_TOKEN" is not set`,
+	}
+	if len(checkErr.Problems) != len(expected) {
+		t.Fatalf("Invalid problems. Actual: %q. Expected: %q", checkErr.Problems, expected)
+	}
+	for i, problem := range checkErr.Problems {
+		if !strings.HasPrefix(problem, expected[i]) {
+			t.Errorf("Invalid problem. Actual: %q. Expected prefix: %q", problem, expected[i])
+		}
+	}
+}
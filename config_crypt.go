@@ -0,0 +1,171 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// EncryptedValuePrefix is the prefix of the encrypted values in the configuration files. The prefix is followed
+// by the base64 encoding of the nonce and the ciphertext (with AES-256 in GCM mode).
+const EncryptedValuePrefix = "enc:AES256GCM:"
+
+// ConfigKeyEnvVar is the environment variable with the key (base64 encoded) to decrypt the configuration values.
+var ConfigKeyEnvVar = "GOVICE_CONFIG_KEY"
+
+// ConfigKeyFileEnvVar is the environment variable with the path of the file with the key (base64 encoded)
+// to decrypt the configuration values.
+var ConfigKeyFileEnvVar = "GOVICE_CONFIG_KEY_FILE"
+
+// configKeySize is the size of an AES-256 key.
+const configKeySize = 32
+
+// GenerateConfigKey generates a random key (base64 encoded) to encrypt configuration values.
+func GenerateConfigKey() (string, error) {
+	key := make([]byte, configKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseConfigKey decodes a key (base64 encoded) to encrypt configuration values.
+func ParseConfigKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key encoding: %s", err)
+	}
+	if len(key) != configKeySize {
+		return nil, fmt.Errorf("invalid key size: %d bytes (expected %d)", len(key), configKeySize)
+	}
+	return key, nil
+}
+
+// EncryptConfigValue encrypts a configuration value with a key. The result includes the EncryptedValuePrefix
+// to be used in a configuration file.
+func EncryptConfigValue(key []byte, value string) (string, error) {
+	gcm, err := newConfigCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	data := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return EncryptedValuePrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecryptConfigValue decrypts a configuration value encrypted with EncryptConfigValue.
+func DecryptConfigValue(key []byte, value string) (string, error) {
+	if !strings.HasPrefix(value, EncryptedValuePrefix) {
+		return "", fmt.Errorf("encrypted value must start with %s", EncryptedValuePrefix)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedValuePrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value encoding: %s", err)
+	}
+	gcm, err := newConfigCipher(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted value: too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt value: wrong key or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+func newConfigCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != configKeySize {
+		return nil, fmt.Errorf("invalid key size: %d bytes (expected %d)", len(key), configKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SetKeyFile sets the file with the key (base64 encoded) to decrypt the configuration values. It overrides the
+// environment variables ConfigKeyEnvVar and ConfigKeyFileEnvVar.
+func (c *ConfigLoader) SetKeyFile(keyFile string) {
+	c.keyFile = keyFile
+}
+
+// readKey reads the key to decrypt the configuration values (see ReadConfigKey) with the .env file variables.
+func (c *ConfigLoader) readKey() ([]byte, error) {
+	return ReadConfigKey(c.keyFile, c.lookupEnv)
+}
+
+// ReadConfigKey reads the key to encrypt or decrypt the configuration values from keyFile or, if it is empty,
+// from the environment variables ConfigKeyEnvVar (the key) or ConfigKeyFileEnvVar (the path of the file with
+// the key). The environment variables are read with lookupEnv (e.g. os.LookupEnv).
+func ReadConfigKey(keyFile string, lookupEnv func(string) (string, bool)) ([]byte, error) {
+	if keyFile == "" {
+		if s, ok := lookupEnv(ConfigKeyEnvVar); ok {
+			key, err := ParseConfigKey(s)
+			if err != nil {
+				return nil, fmt.Errorf("environment variable %s: %s", ConfigKeyEnvVar, err)
+			}
+			return key, nil
+		}
+		var ok bool
+		if keyFile, ok = lookupEnv(ConfigKeyFileEnvVar); !ok {
+			return nil, fmt.Errorf("no decryption key (set the environment variable %s or %s)", ConfigKeyEnvVar, ConfigKeyFileEnvVar)
+		}
+	}
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read decryption key: %s", err)
+	}
+	key, err := ParseConfigKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("key file %s: %s", keyFile, err)
+	}
+	return key, nil
+}
+
+// decrypt replaces the encrypted values of the document with the decrypted values. The key is only read
+// if there is any encrypted value.
+func (c *ConfigLoader) decrypt(doc *configDocument) error {
+	var key []byte
+	return doc.mapStrings(func(path, s string) (string, error) {
+		if !strings.HasPrefix(s, EncryptedValuePrefix) {
+			return s, nil
+		}
+		if key == nil {
+			var err error
+			if key, err = c.readKey(); err != nil {
+				return "", err
+			}
+		}
+		return DecryptConfigValue(key, s)
+	})
+}
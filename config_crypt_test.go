@@ -0,0 +1,180 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestConfigKey(t *testing.T) (string, []byte) {
+	encoded, err := GenerateConfigKey()
+	if err != nil {
+		t.Fatalf("Error generating key. %s", err)
+	}
+	key, err := ParseConfigKey(encoded)
+	if err != nil {
+		t.Fatalf("Error parsing key. %s", err)
+	}
+	return encoded, key
+}
+
+func TestEncryptConfigValue(t *testing.T) {
+	_, key := newTestConfigKey(t)
+	_, otherKey := newTestConfigKey(t)
+	encrypted, err := EncryptConfigValue(key, "s3cr3t")
+	if err != nil {
+		t.Fatalf("Error encrypting value. %s", err)
+	}
+	if !strings.HasPrefix(encrypted, EncryptedValuePrefix) {
+		t.Errorf("Invalid encrypted value: %s", encrypted)
+	}
+	tests := []struct {
+		name     string
+		key      []byte
+		value    string
+		expected string
+		err      string
+	}{
+		{"valid", key, encrypted, "s3cr3t", ""},
+		{"wrong key", otherKey, encrypted, "", "cannot decrypt value: wrong key or corrupted value"},
+		{"invalid key size", key[:16], encrypted, "", "invalid key size: 16 bytes (expected 32)"},
+		{"missing prefix", key, "s3cr3t", "", "encrypted value must start with enc:AES256GCM:"},
+		{"invalid encoding", key, EncryptedValuePrefix + "!!", "", "invalid encrypted value encoding"},
+		{"too short", key, EncryptedValuePrefix + "AAAA", "", "invalid encrypted value: too short"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := DecryptConfigValue(tt.key, tt.value)
+			if tt.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+					t.Errorf("Invalid error. Actual: %v. Expected: %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil || actual != tt.expected {
+				t.Errorf("Invalid value. Actual: %s (%v). Expected: %s", actual, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseConfigKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		err  string
+	}{
+		{"valid", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n", ""},
+		{"invalid encoding", "not a key", "invalid key encoding"},
+		{"invalid size", "AAAA", "invalid key size: 3 bytes (expected 32)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfigKey(tt.key)
+			if tt.err == "" && err != nil {
+				t.Errorf("Unexpected error. %s", err)
+			}
+			if tt.err != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.err)) {
+				t.Errorf("Invalid error. Actual: %v. Expected: %s", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoadEncryptedConfig(t *testing.T) {
+	encodedKey, key := newTestConfigKey(t)
+	encrypted, err := EncryptConfigValue(key, "/secret")
+	if err != nil {
+		t.Fatalf("Error encrypting value. %s", err)
+	}
+	configFile := writeTestFile(t, "config.json", `{"basePath": "`+encrypted+`", "logLevel": "INFO"}`)
+	dir := filepath.Dir(configFile)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "config.key")
+	if err := ioutil.WriteFile(keyFile, []byte(encodedKey+"\n"), 0600); err != nil {
+		t.Fatalf("Error writing key file. %s", err)
+	}
+	os.Unsetenv("ADDRESS")
+	os.Unsetenv("LOG_LEVEL")
+	os.Unsetenv(ConfigKeyEnvVar)
+	os.Unsetenv(ConfigKeyFileEnvVar)
+
+	tests := []struct {
+		name    string
+		keyFile string
+		env     map[string]string
+		err     string
+	}{
+		{"key file", keyFile, nil, ""},
+		{"key env", "", map[string]string{ConfigKeyEnvVar: encodedKey}, ""},
+		{"key file env", "", map[string]string{ConfigKeyFileEnvVar: keyFile}, ""},
+		{"missing key", "", nil, `key "basePath": no decryption key (set the environment variable GOVICE_CONFIG_KEY or GOVICE_CONFIG_KEY_FILE)`},
+		{"missing key file", filepath.Join(dir, "missing.key"), nil, `key "basePath": cannot read decryption key`},
+		{"invalid key env", "", map[string]string{ConfigKeyEnvVar: "AAAA"}, `key "basePath": environment variable GOVICE_CONFIG_KEY: invalid key size`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				os.Setenv(name, value)
+				defer os.Unsetenv(name)
+			}
+			loader := NewConfigLoader(configFile)
+			loader.SetKeyFile(tt.keyFile)
+			var cfg config
+			err := loader.Load(&cfg)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Invalid error. Actual: %v. Expected: %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error loading config. %s", err)
+			}
+			if cfg.BasePath != "/secret" {
+				t.Errorf("Invalid decrypted value. Actual: %s", cfg.BasePath)
+			}
+		})
+	}
+
+	// The key is not required without encrypted values
+	if err := NewConfigLoader("testdata/config.json").Load(&config{}); err != nil {
+		t.Errorf("Error loading config without encrypted values. %s", err)
+	}
+}
+
+func TestReadConfigKey(t *testing.T) {
+	encodedKey, key := newTestConfigKey(t)
+	env := map[string]string{ConfigKeyEnvVar: encodedKey}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	actual, err := ReadConfigKey("", lookupEnv)
+	if err != nil || !bytes.Equal(actual, key) {
+		t.Errorf("Invalid key from lookupEnv. Actual: %v. Error: %v", actual, err)
+	}
+	if _, err := ReadConfigKey("", func(string) (string, bool) { return "", false }); err == nil {
+		t.Errorf("Expected error without key")
+	}
+}
@@ -0,0 +1,150 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"text/tabwriter"
+)
+
+// ConfigChange is a change of a configuration value identified by its path of json names (e.g. "database.host").
+// Old is nil for added values, and New is nil for removed values. The values of the secret fields are redacted.
+type ConfigChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// ConfigDiff is the list of changes between two configurations, sorted by path.
+type ConfigDiff []ConfigChange
+
+// DiffConfig compares two configurations (structs or pointers to structs of the same type) field by field.
+// Objects are compared key by key; any other value (including arrays) is compared as a whole. The secret
+// fields are redacted (see Redact), but their changes are also reported.
+func DiffConfig(oldConfig, newConfig interface{}) (ConfigDiff, error) {
+	oldValues, err := diffValues(oldConfig, false)
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := diffValues(newConfig, false)
+	if err != nil {
+		return nil, err
+	}
+	oldRedacted, err := diffValues(oldConfig, true)
+	if err != nil {
+		return nil, err
+	}
+	newRedacted, err := diffValues(newConfig, true)
+	if err != nil {
+		return nil, err
+	}
+	var diff ConfigDiff
+	appendConfigChanges(&diff, "", oldValues, newValues, oldRedacted, newRedacted)
+	return diff, nil
+}
+
+// diffValues converts a configuration into a generic JSON value (optionally redacted) to be compared.
+func diffValues(config interface{}, redacted bool) (interface{}, error) {
+	if redacted {
+		config = Redact(config)
+	}
+	data, err := marshalConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("Error comparing configuration. %s", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("Error comparing configuration. %s", err)
+	}
+	return value, nil
+}
+
+func appendConfigChanges(diff *ConfigDiff, path string, oldValue, newValue, oldRedacted, newRedacted interface{}) {
+	oldObject, oldIsObject := oldValue.(map[string]interface{})
+	newObject, newIsObject := newValue.(map[string]interface{})
+	if oldIsObject && newIsObject {
+		oldRedactedObject, _ := oldRedacted.(map[string]interface{})
+		newRedactedObject, _ := newRedacted.(map[string]interface{})
+		keys := make(map[string]interface{}, len(oldObject)+len(newObject))
+		for key := range oldObject {
+			keys[key] = nil
+		}
+		for key := range newObject {
+			keys[key] = nil
+		}
+		for _, key := range sortedKeys(keys) {
+			appendConfigChanges(diff, joinConfigPath(path, key), oldObject[key], newObject[key], oldRedactedObject[key], newRedactedObject[key])
+		}
+		return
+	}
+	if reflect.DeepEqual(oldValue, newValue) {
+		return
+	}
+	// A secret changed but its redacted values are the same
+	if reflect.DeepEqual(oldRedacted, newRedacted) {
+		oldRedacted, newRedacted = RedactedValue, RedactedValue
+	}
+	*diff = append(*diff, ConfigChange{Path: path, Old: oldRedacted, New: newRedacted})
+}
+
+// WriteTable writes the diff as a plain text table with the columns: PATH, OLD and NEW.
+func (d ConfigDiff) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tOLD\tNEW")
+	for _, change := range d {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", change.Path, formatDiffValue(change.Old), formatDiffValue(change.New))
+	}
+	return tw.Flush()
+}
+
+func (d ConfigDiff) String() string {
+	var buf bytes.Buffer
+	d.WriteTable(&buf)
+	return buf.String()
+}
+
+func formatDiffValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// ConfigDiffLogContext is a log context with the changes of the configuration.
+type ConfigDiffLogContext struct {
+	Changes ConfigDiff `json:"configChanges"`
+}
+
+// LogContext returns a log context with the changes of the configuration.
+func (d ConfigDiff) LogContext() *ConfigDiffLogContext {
+	changes := d
+	if changes == nil {
+		changes = ConfigDiff{}
+	}
+	return &ConfigDiffLogContext{Changes: changes}
+}
+
+// Log writes the changes of the configuration as an INFO log record with a message.
+func (d ConfigDiff) Log(logger *Logger, message string) {
+	logger.InfoC(d.LogContext(), message)
+}
@@ -0,0 +1,97 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffConfig(t *testing.T) {
+	base := secretConfig{
+		Address:  ":80",
+		Token:    "t0k3n",
+		Database: secretDBConfig{User: "admin", Password: "p4ss"},
+		Replicas: []*secretDBConfig{{User: "replica"}},
+	}
+	tests := []struct {
+		name     string
+		update   func(cfg *secretConfig)
+		expected ConfigDiff
+	}{
+		{"no changes", func(cfg *secretConfig) {}, nil},
+		{"value", func(cfg *secretConfig) { cfg.Address = ":8080" }, ConfigDiff{{Path: "address", Old: ":80", New: ":8080"}}},
+		{"nested value", func(cfg *secretConfig) { cfg.Database.User = "root" }, ConfigDiff{{Path: "database.user", Old: "admin", New: "root"}}},
+		{"secret", func(cfg *secretConfig) { cfg.Database.Password = "n3w" }, ConfigDiff{{Path: "database.password", Old: RedactedValue, New: RedactedValue}}},
+		{"removed secret", func(cfg *secretConfig) { cfg.Token = "" }, ConfigDiff{{Path: "token", Old: RedactedValue, New: nil}}},
+		{"array", func(cfg *secretConfig) { cfg.Replicas = nil }, ConfigDiff{{Path: "replicas", Old: []interface{}{map[string]interface{}{"user": "replica", "password": ""}}, New: nil}}},
+		{"several values", func(cfg *secretConfig) { cfg.Address, cfg.Database.User = "", "root" }, ConfigDiff{
+			{Path: "address", Old: ":80", New: ""},
+			{Path: "database.user", Old: "admin", New: "root"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := base
+			tt.update(&updated)
+			actual, err := DiffConfig(&base, &updated)
+			if err != nil {
+				t.Fatalf("Error comparing config. %s", err)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Invalid diff. Actual: %#v. Expected: %#v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiffConfigTextValues(t *testing.T) {
+	oldConfig := typesBackend{Timeout: time.Second}
+	newConfig := typesBackend{URL: &url.URL{Scheme: "http", Host: "backend"}, Timeout: time.Minute}
+	diff, err := DiffConfig(oldConfig, newConfig)
+	if err != nil {
+		t.Fatalf("Error comparing config. %s", err)
+	}
+	expected := ConfigDiff{{Path: "timeout", Old: "1s", New: "1m0s"}, {Path: "url", Old: nil, New: "http://backend"}}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Invalid diff. Actual: %+v. Expected: %+v", diff, expected)
+	}
+}
+
+func TestDiffConfigError(t *testing.T) {
+	if _, err := DiffConfig(map[string]interface{}{"ch": make(chan int)}, nil); err == nil {
+		t.Errorf("Expected error comparing unsupported values")
+	}
+}
+
+func TestConfigDiffLog(t *testing.T) {
+	diff := ConfigDiff{{Path: "address", Old: ":80", New: ":8080"}, {Path: "token", Old: nil, New: RedactedValue}}
+	expected := "PATH     OLD    NEW\naddress  \":80\"  \":8080\"\ntoken    null   \"******\"\n"
+	if actual := diff.String(); actual != expected {
+		t.Errorf("Invalid table. Actual: %q. Expected: %q", actual, expected)
+	}
+	var buf bytes.Buffer
+	diff.Log(&Logger{out: &buf, logLevel: infoLevel}, "Configuration loaded")
+	if !strings.Contains(buf.String(), `"lvl":"INFO","configChanges":[{"path":"address","old":":80","new":":8080"},{"path":"token","old":null,"new":"******"}],"msg":"Configuration loaded"`) {
+		t.Errorf("Invalid log. Actual: %s", buf.String())
+	}
+}
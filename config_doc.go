@@ -0,0 +1,212 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// ConfigFieldDoc documents a configuration field.
+type ConfigFieldDoc struct {
+	Field       string `json:"field"`
+	Key         string `json:"key"`
+	Env         string `json:"env,omitempty"`
+	Type        string `json:"type"`
+	Default     string `json:"default,omitempty"`
+	Secret      bool   `json:"secret,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ConfigDoc is the documentation of every field of a configuration struct.
+type ConfigDoc []ConfigFieldDoc
+
+// GetConfigDoc documents the fields of a configuration struct (or a pointer to it): Go field path, JSON key
+// path, environment variable, type, default value, whether it is secret and the description (desc struct tag).
+// The default value is taken from the configuration file (if configFile is not empty), the envDefault struct
+// tag or the value of the field in config, in this order. Environment variables are ignored. The default
+// value of secret fields is redacted.
+// Only the env struct tags are documented; use ConfigLoader.GetConfigDoc to include the environment variables
+// derived from the field paths (see SetEnvPrefix).
+func GetConfigDoc(config interface{}, configFile string) (ConfigDoc, error) {
+	loader := &ConfigLoader{}
+	if configFile != "" {
+		loader = NewConfigLoader(configFile)
+	}
+	return loader.GetConfigDoc(config)
+}
+
+// GetConfigDoc works like the GetConfigDoc function, but the environment variables are bound as in Load
+// (including the names derived with SetEnvPrefix) and the default values are taken from every configuration layer.
+func (c *ConfigLoader) GetConfigDoc(config interface{}) (ConfigDoc, error) {
+	v := reflect.ValueOf(config)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Error documenting configuration. Expected a struct")
+	}
+	defaults := reflect.New(v.Type())
+	defaults.Elem().Set(copyConfigValue(v))
+	root := defaults.Elem()
+	fields := getConfigFields(root.Type())
+	for _, f := range fields {
+		name, _ := c.fieldEnv(f)
+		def, ok := f.field.Tag.Lookup("envDefault")
+		if name == "" || !ok {
+			continue
+		}
+		value, _ := configFieldValue(root, f.index, true)
+		if err := setConfigValue(value, def, f.field.Tag.Get("envSeparator")); err != nil {
+			return nil, fmt.Errorf("Error documenting configuration. Invalid envDefault tag of %s: %s", f.path, err)
+		}
+	}
+	doc, err := c.readDocument()
+	if err != nil {
+		return nil, fmt.Errorf("Error documenting configuration. %s", err)
+	}
+	if doc != nil {
+		if err := doc.unmarshal(defaults.Interface()); err != nil {
+			return nil, fmt.Errorf("Error documenting configuration. %s", err)
+		}
+	}
+	configDoc := make(ConfigDoc, 0, len(fields))
+	for _, f := range fields {
+		env, _ := c.fieldEnv(f)
+		fieldDoc := ConfigFieldDoc{
+			Field:       goFieldPath(root.Type(), f.index),
+			Key:         f.path,
+			Env:         env,
+			Type:        f.field.Type.String(),
+			Secret:      isSecretField(f.field),
+			Description: f.field.Tag.Get("desc"),
+		}
+		if value, ok := configFieldValue(root, f.index, false); ok {
+			fieldDoc.Default = formatConfigValue(value, f.field.Tag.Get("envSeparator"))
+		}
+		if fieldDoc.Secret && fieldDoc.Default != "" {
+			fieldDoc.Default = RedactedValue
+		}
+		configDoc = append(configDoc, fieldDoc)
+	}
+	return configDoc, nil
+}
+
+// goFieldPath returns the path of a field with the Go field names. Embedded structs are omitted
+// because their fields are promoted.
+func goFieldPath(t reflect.Type, index []int) string {
+	var names []string
+	for n, i := range index {
+		field := derefType(t).Field(i)
+		if name, _ := configFieldName(field); name != "" || n == len(index)-1 {
+			names = append(names, field.Name)
+		}
+		t = field.Type
+	}
+	return strings.Join(names, ".")
+}
+
+// formatConfigValue returns the string representation of a value as it would be set in an environment variable.
+// Zero values are returned as an empty string.
+func formatConfigValue(v reflect.Value, separator string) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		return formatConfigValue(v.Elem(), separator)
+	}
+	if v.IsZero() {
+		return ""
+	}
+	if isTextConfigType(v.Type()) {
+		return formatTextConfigValue(v)
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	switch v.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface())
+	case reflect.Slice:
+		if separator == "" {
+			separator = ","
+		}
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatConfigValue(v.Index(i), separator)
+		}
+		return strings.Join(items, separator)
+	}
+	data, _ := json.Marshal(v.Interface())
+	return string(data)
+}
+
+// WriteTable writes the documentation as a plain text table with the columns: FIELD, KEY, ENV, TYPE, DEFAULT,
+// SECRET and DESCRIPTION. It is suitable for the help of a command.
+func (d ConfigDoc) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tKEY\tENV\tTYPE\tDEFAULT\tSECRET\tDESCRIPTION")
+	for _, f := range d {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%t\t%s\n", f.Field, f.Key, f.Env, f.Type, f.Default, f.Secret, f.Description)
+	}
+	return tw.Flush()
+}
+
+// WriteMarkdown writes the documentation as a Markdown table.
+func (d ConfigDoc) WriteMarkdown(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("| Field | Key | Env | Type | Default | Secret | Description |\n")
+	buf.WriteString("|-------|-----|-----|------|---------|--------|-------------|\n")
+	for _, f := range d {
+		secret := ""
+		if f.Secret {
+			secret = "yes"
+		}
+		cells := []string{markdownCode(f.Field), markdownCode(f.Key), markdownCode(f.Env), markdownCode(f.Type),
+			markdownCode(f.Default), secret, markdownCell(f.Description)}
+		buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func (d ConfigDoc) String() string {
+	var buf bytes.Buffer
+	d.WriteTable(&buf)
+	return buf.String()
+}
+
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownCell(s) + "`"
+}
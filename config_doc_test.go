@@ -0,0 +1,153 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type docDatabaseConfig struct {
+	URL      string `json:"url" env:"DB_URL" desc:"Database URL"`
+	Password string `json:"password" env:"DB_PASSWORD" secret:"true" desc:"Database password"`
+}
+
+type docConfig struct {
+	Address  string            `json:"address" env:"ADDRESS" desc:"Listening address"`
+	LogLevel string            `json:"logLevel" env:"LOG_LEVEL" envDefault:"WARN" desc:"Log level | DEBUG, INFO..."`
+	Timeout  time.Duration     `json:"timeout" env:"TIMEOUT"`
+	Realms   []string          `json:"realms" env:"REALMS"`
+	Labels   map[string]string `json:"labels"`
+	Database docDatabaseConfig `json:"database"`
+}
+
+func TestGetConfigDoc(t *testing.T) {
+	os.Setenv("ADDRESS", ":9999")
+	defer os.Unsetenv("ADDRESS")
+	cfg := docConfig{
+		Timeout:  5 * time.Second,
+		Realms:   []string{"es", "uk"},
+		Database: docDatabaseConfig{Password: "secret"},
+	}
+	doc, err := GetConfigDoc(&cfg, "testdata/config.json")
+	if err != nil {
+		t.Fatalf("Error documenting config. %s", err)
+	}
+	expected := ConfigDoc{
+		{Field: "Address", Key: "address", Env: "ADDRESS", Type: "string", Default: ":80", Description: "Listening address"},
+		{Field: "LogLevel", Key: "logLevel", Env: "LOG_LEVEL", Type: "string", Default: "INFO", Description: "Log level | DEBUG, INFO..."},
+		{Field: "Timeout", Key: "timeout", Env: "TIMEOUT", Type: "time.Duration", Default: "5s"},
+		{Field: "Realms", Key: "realms", Env: "REALMS", Type: "[]string", Default: "es,uk"},
+		{Field: "Labels", Key: "labels", Type: "map[string]string"},
+		{Field: "Database.URL", Key: "database.url", Env: "DB_URL", Type: "string", Description: "Database URL"},
+		{Field: "Database.Password", Key: "database.password", Env: "DB_PASSWORD", Type: "string", Default: RedactedValue, Secret: true, Description: "Database password"},
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("Invalid config doc. Actual: %+v. Expected: %+v", doc, expected)
+	}
+	if cfg.Address != "" {
+		t.Errorf("Config should not be modified. Actual: %+v", cfg)
+	}
+
+	doc, err = GetConfigDoc(docConfig{}, "")
+	if err != nil {
+		t.Fatalf("Error documenting config without file. %s", err)
+	}
+	if doc[1].Default != "WARN" {
+		t.Errorf("Invalid default from envDefault. Actual: %s", doc[1].Default)
+	}
+}
+
+func TestLoaderGetConfigDoc(t *testing.T) {
+	loader := NewConfigLoader("testdata/config.json")
+	loader.SetEnvPrefix("USERS_")
+	doc, err := loader.GetConfigDoc(&docConfig{})
+	if err != nil {
+		t.Fatalf("Error documenting config. %s", err)
+	}
+	envs := make([]string, len(doc))
+	for i, f := range doc {
+		envs[i] = f.Env
+	}
+	expected := []string{"ADDRESS", "LOG_LEVEL", "TIMEOUT", "REALMS", "USERS_LABELS", "DB_URL", "DB_PASSWORD"}
+	if !reflect.DeepEqual(envs, expected) {
+		t.Errorf("Invalid environment variables. Actual: %v. Expected: %v", envs, expected)
+	}
+	if doc[0].Default != ":80" {
+		t.Errorf("Invalid default from the configuration file. Actual: %s", doc[0].Default)
+	}
+
+	// envDefault also applies to the derived environment variables
+	doc, err = (&ConfigLoader{envPrefix: "USERS_", autoEnv: true}).GetConfigDoc(&struct {
+		Port int `json:"port" envDefault:"8080"`
+	}{})
+	if err != nil {
+		t.Fatalf("Error documenting config. %s", err)
+	}
+	if doc[0].Env != "USERS_PORT" || doc[0].Default != "8080" {
+		t.Errorf("Invalid doc of derived environment variable. Actual: %+v", doc[0])
+	}
+}
+
+func TestGetConfigDocErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     interface{}
+		configFile string
+	}{
+		{"not a struct", "config", ""},
+		{"missing file", &docConfig{}, "testdata/notExistent.json"},
+		{"invalid envDefault", &struct {
+			Port int `json:"port" env:"PORT" envDefault:"a"`
+		}{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := GetConfigDoc(tt.config, tt.configFile); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
+
+func TestConfigDocWrite(t *testing.T) {
+	doc := ConfigDoc{
+		{Field: "Address", Key: "address", Env: "ADDRESS", Type: "string", Default: ":80", Description: "Listening address"},
+		{Field: "Password", Key: "password", Env: "PASSWORD", Type: "string", Secret: true, Description: "A | B"},
+	}
+	expectedTable := "FIELD     KEY       ENV       TYPE    DEFAULT  SECRET  DESCRIPTION\n" +
+		"Address   address   ADDRESS   string  :80      false   Listening address\n" +
+		"Password  password  PASSWORD  string           true    A | B\n"
+	if actual := doc.String(); actual != expectedTable {
+		t.Errorf("Invalid table. Actual:\n%s\nExpected:\n%s", actual, expectedTable)
+	}
+	expectedMarkdown := "| Field | Key | Env | Type | Default | Secret | Description |\n" +
+		"|-------|-----|-----|------|---------|--------|-------------|\n" +
+		"| `Address` | `address` | `ADDRESS` | `string` | `:80` |  | Listening address |\n" +
+		"| `Password` | `password` | `PASSWORD` | `string` |  | yes | A \\| B |\n"
+	var buf bytes.Buffer
+	if err := doc.WriteMarkdown(&buf); err != nil {
+		t.Fatalf("Error writing markdown. %s", err)
+	}
+	if actual := buf.String(); actual != expectedMarkdown {
+		t.Errorf("Invalid markdown. Actual:\n%s\nExpected:\n%s", actual, expectedMarkdown)
+	}
+}
@@ -0,0 +1,155 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var dotEnvKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// SetDotEnvFile sets a .env file with environment variables for local development. The variables of the
+// file are used as if they were set in the environment, but the real environment variables take precedence.
+// The file is optional: it is skipped if it does not exist.
+func (c *ConfigLoader) SetDotEnvFile(dotEnvFile string) {
+	c.dotEnvFile = dotEnvFile
+}
+
+// readDotEnv reads the .env file (if any) before loading the configuration.
+func (c *ConfigLoader) readDotEnv() error {
+	c.dotEnv = nil
+	if c.dotEnvFile == "" {
+		return nil
+	}
+	vars, err := readDotEnvFile(c.dotEnvFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	c.dotEnv = vars
+	return nil
+}
+
+// lookupEnv returns the value of an environment variable from the environment or from the .env file.
+func (c *ConfigLoader) lookupEnv(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := c.dotEnv[name]
+	return value, ok
+}
+
+// envOrigin returns the origin of an environment variable that is set (from the environment or the .env file).
+func (c *ConfigLoader) envOrigin(name string) configOrigin {
+	if _, ok := os.LookupEnv(name); !ok {
+		if _, ok := c.dotEnv[name]; ok {
+			return configOrigin{source: ConfigSourceDotEnv, name: c.dotEnvFile + ":" + name}
+		}
+	}
+	return configOrigin{source: ConfigSourceEnv, name: name}
+}
+
+// readDotEnvFile parses a .env file. Every line is a KEY=VALUE assignment (optionally preceded by "export"),
+// a comment (starting with #) or blank. Values may be quoted: double quoted values support the escape
+// sequences \n, \t, \" and \\; single quoted values are literal. Unquoted values end at an inline comment
+// (" #").
+func readDotEnvFile(dotEnvFile string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(dotEnvFile)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimSpace(strings.TrimPrefix(text, "export "))
+		i := strings.IndexByte(text, '=')
+		if i < 0 {
+			return nil, &ConfigFileError{File: dotEnvFile, Line: line, Err: errors.New("expected KEY=VALUE")}
+		}
+		key := strings.TrimSpace(text[:i])
+		if !dotEnvKeyRegexp.MatchString(key) {
+			return nil, &ConfigFileError{File: dotEnvFile, Line: line, Err: fmt.Errorf("invalid variable name %q", key)}
+		}
+		value, err := parseDotEnvValue(strings.TrimSpace(text[i+1:]))
+		if err != nil {
+			return nil, &ConfigFileError{File: dotEnvFile, Key: key, Line: line, Err: err}
+		}
+		vars[key] = value
+	}
+	return vars, scanner.Err()
+}
+
+func parseDotEnvValue(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	switch quote := s[0]; quote {
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", errors.New("unterminated single quoted value")
+		}
+		return s[1 : end+1], checkDotEnvTrailing(s[end+2:])
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '"':
+				return b.String(), checkDotEnvTrailing(s[i+1:])
+			case '\\':
+				if i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					default:
+						b.WriteByte(s[i])
+					}
+					continue
+				}
+			}
+			b.WriteByte(s[i])
+		}
+		return "", errors.New("unterminated double quoted value")
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// checkDotEnvTrailing checks that there is only a comment after a quoted value.
+func checkDotEnvTrailing(s string) error {
+	s = strings.TrimSpace(s)
+	if s != "" && !strings.HasPrefix(s, "#") {
+		return fmt.Errorf("unexpected characters after quoted value: %s", s)
+	}
+	return nil
+}
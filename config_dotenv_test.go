@@ -0,0 +1,144 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadDotEnvFile(t *testing.T) {
+	file := writeTestFile(t, ".env", `# Local configuration
+ADDRESS=:8080
+export LOG_LEVEL=DEBUG
+  BASE_PATH = /api # inline comment
+EMPTY=
+DOUBLE="hello \"world\"\nbye" # comment
+SINGLE='no \n escape'
+HASH=a#b
+URL=http://${HOST}:80
+`)
+	defer os.RemoveAll(filepath.Dir(file))
+	actual, err := readDotEnvFile(file)
+	if err != nil {
+		t.Fatalf("Error reading .env file. %s", err)
+	}
+	expected := map[string]string{
+		"ADDRESS":   ":8080",
+		"LOG_LEVEL": "DEBUG",
+		"BASE_PATH": "/api",
+		"EMPTY":     "",
+		"DOUBLE":    "hello \"world\"\nbye",
+		"SINGLE":    `no \n escape`,
+		"HASH":      "a#b",
+		"URL":       "http://${HOST}:80",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Invalid variables. Actual: %+v. Expected: %+v", actual, expected)
+	}
+}
+
+func TestReadDotEnvFileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"missing equal", "ADDRESS=:80\nLOG_LEVEL\n", `:2: expected KEY=VALUE`},
+		{"invalid name", "1ADDRESS=:80\n", `:1: invalid variable name "1ADDRESS"`},
+		{"unterminated double quote", "A=\"abc\n", `:1: key "A": unterminated double quoted value`},
+		{"unterminated single quote", "A='abc\n", `:1: key "A": unterminated single quoted value`},
+		{"trailing characters", "A=\"abc\" def\n", `:1: key "A": unexpected characters after quoted value: def`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeTestFile(t, ".env", tt.content)
+			defer os.RemoveAll(filepath.Dir(file))
+			_, err := readDotEnvFile(file)
+			if err == nil || !strings.HasSuffix(err.Error(), tt.expected) {
+				t.Errorf("Invalid error. Actual: %v. Expected suffix: %s", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	dotEnvFile := writeTestFile(t, ".env", "ADDRESS=:8080\nLOG_LEVEL=DEBUG\nREALM=uk\n")
+	defer os.RemoveAll(filepath.Dir(dotEnvFile))
+	os.Unsetenv("ADDRESS")
+	os.Unsetenv("REALM")
+	os.Setenv("LOG_LEVEL", "ERROR")
+	defer os.Unsetenv("LOG_LEVEL")
+	loader := NewConfigLoader("testdata/config.json")
+	loader.SetDotEnvFile(dotEnvFile)
+	var cfg config
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+	expected := config{Address: ":8080", BasePath: "/users", LogLevel: "ERROR", Realm: "uk"}
+	if cfg != expected {
+		t.Errorf("Invalid config. Actual: %+v. Expected: %+v", cfg, expected)
+	}
+	if _, ok := os.LookupEnv("ADDRESS"); ok {
+		t.Errorf("The .env file must not modify the environment")
+	}
+	provenance := loader.GetProvenance()
+	if source := provenance.Get("address"); source.String() != "dotEnv:"+dotEnvFile+":ADDRESS" {
+		t.Errorf("Invalid source for address. Actual: %s", source)
+	}
+	if source := provenance.Get("logLevel"); source.String() != "env:LOG_LEVEL" {
+		t.Errorf("Invalid source for logLevel. Actual: %s", source)
+	}
+
+	// A missing .env file is skipped
+	loader.SetDotEnvFile(filepath.Join(filepath.Dir(dotEnvFile), "missing.env"))
+	if err := loader.Load(&cfg); err != nil {
+		t.Errorf("Error loading config without .env file. %s", err)
+	}
+}
+
+func TestLoadDotEnvProfilesAndExpand(t *testing.T) {
+	dotEnvFile := writeTestFile(t, ".env", "GOVICE_PROFILE=production\nTEST_HOST=localhost\nTEST_URL=http://${TEST_HOST}:${TEST_PORT}\n")
+	defer os.RemoveAll(filepath.Dir(dotEnvFile))
+	for _, name := range []string{"ADDRESS", "REALM", ProfileEnvVar, "TEST_HOST", "TEST_URL"} {
+		os.Unsetenv(name)
+	}
+	os.Setenv("TEST_PORT", "8080")
+	defer os.Unsetenv("TEST_PORT")
+	loader := NewConfigLoader("testdata/config.json")
+	loader.SetDotEnvFile(dotEnvFile)
+	if err := loader.AddEnvProfiles(); err != nil {
+		t.Fatalf("Error adding profiles. %s", err)
+	}
+	var cfg struct {
+		config
+		URL string `json:"url" env:"TEST_URL" envExpand:"true"`
+	}
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+	if cfg.Realm != "uk" {
+		t.Errorf("Profile from the .env file not applied. Actual: %+v", cfg)
+	}
+	if cfg.URL != "http://localhost:8080" {
+		t.Errorf("Invalid expanded value. Actual: %s", cfg.URL)
+	}
+}
@@ -0,0 +1,139 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// parseEnvTag splits the env struct tag into the environment variable name and its options (e.g. required).
+func parseEnvTag(tag string) (string, []string) {
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:]
+}
+
+// SetEnvPrefix binds every configuration field without env struct tag to an environment variable whose name is
+// derived from the JSON path of the field with a prefix. For example, with the prefix "USERS_", the field
+// database.pool.maxSize is bound to USERS_DATABASE_POOL_MAX_SIZE. Slices are set as a list of items and maps
+// as a list of key=value items (separated by commas or by the envSeparator struct tag).
+func (c *ConfigLoader) SetEnvPrefix(prefix string) {
+	c.envPrefix = prefix
+	c.autoEnv = true
+}
+
+// fieldEnv returns the environment variable bound to a field and its options (e.g. required). The name is
+// set with the env struct tag or derived from the field path (see SetEnvPrefix).
+func (c *ConfigLoader) fieldEnv(f configField) (string, []string) {
+	name, opts := parseEnvTag(f.field.Tag.Get("env"))
+	if name == "" && c.autoEnv {
+		name = c.envPrefix + configEnvName(f.path)
+	}
+	return name, opts
+}
+
+// configEnvName converts a configuration path into an environment variable name
+// (e.g. database.pool.maxSize into DATABASE_POOL_MAX_SIZE).
+func configEnvName(path string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(configFlagName(path)))
+}
+
+// applyEnvDefaults sets the default value (envDefault struct tag) of the fields whose environment
+// variable is not set. These defaults have the lowest precedence (they are overridden by the files).
+func (c *ConfigLoader) applyEnvDefaults(root reflect.Value, fields []configField) error {
+	var errs []string
+	for _, f := range fields {
+		name, _ := c.fieldEnv(f)
+		def, ok := f.field.Tag.Lookup("envDefault")
+		if name == "" || !ok {
+			continue
+		}
+		if _, ok := c.lookupEnv(name); ok {
+			continue
+		}
+		value, _ := configFieldValue(root, f.index, true)
+		if err := setConfigValue(value, def, f.field.Tag.Get("envSeparator")); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid default value for environment variable %q: %s", name, err))
+		}
+	}
+	return joinConfigErrors(errs)
+}
+
+// applyEnv sets the fields whose environment variable is set. Note that an empty value is also applied
+// (setting the zero value of the field). The value of secret fields may also be read from a file
+// (see SecretsDir).
+func (c *ConfigLoader) applyEnv(root reflect.Value, fields []configField) error {
+	var errs []string
+	for _, f := range fields {
+		name, opts := c.fieldEnv(f)
+		if name == "" {
+			continue
+		}
+		required := false
+		for _, opt := range opts {
+			switch opt {
+			case "":
+			case "required":
+				required = true
+			default:
+				errs = append(errs, fmt.Sprintf("env tag option %q not supported", opt))
+			}
+		}
+		s, ok := c.lookupEnv(name)
+		origin := c.envOrigin(name)
+		if !ok && isSecretField(f.field) {
+			var err error
+			if s, origin.name, ok, err = readSecretFile(name, c.lookupEnv); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid secret file for environment variable %q: %s", name, err))
+				continue
+			}
+			origin.source = ConfigSourceSecretFile
+		}
+		if !ok {
+			if required {
+				errs = append(errs, fmt.Sprintf("required environment variable %q is not set", name))
+			}
+			continue
+		}
+		if strings.ToLower(f.field.Tag.Get("envExpand")) == "true" {
+			s = os.Expand(s, func(name string) string {
+				value, _ := c.lookupEnv(name)
+				return value
+			})
+		}
+		value, _ := configFieldValue(root, f.index, true)
+		if s == "" {
+			value.Set(reflect.Zero(value.Type()))
+		} else if err := setConfigValue(value, s, f.field.Tag.Get("envSeparator")); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid value for environment variable %q: %s", name, err))
+			continue
+		}
+		c.origins[f.path] = origin
+	}
+	return joinConfigErrors(errs)
+}
+
+func joinConfigErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, ". "))
+}
@@ -0,0 +1,190 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type featureConfig struct {
+	Enabled  bool          `json:"enabled" env:"FEATURE_ENABLED"`
+	Retries  int           `json:"retries" env:"FEATURE_RETRIES"`
+	Name     string        `json:"name" env:"FEATURE_NAME"`
+	Timeout  time.Duration `json:"timeout" env:"FEATURE_TIMEOUT" envDefault:"5s"`
+	Realms   []string      `json:"realms" env:"FEATURE_REALMS" envSeparator:";"`
+	Database struct {
+		Host string `json:"host" env:"FEATURE_DB_HOST"`
+		Port int    `json:"port"`
+	} `json:"database"`
+}
+
+func clearFeatureEnv() {
+	for _, name := range []string{"FEATURE_ENABLED", "FEATURE_RETRIES", "FEATURE_NAME", "FEATURE_TIMEOUT", "FEATURE_REALMS", "FEATURE_DB_HOST"} {
+		os.Unsetenv(name)
+	}
+}
+
+func TestGetConfigPrecedence(t *testing.T) {
+	file := writeTestFile(t, "config.json", `{"enabled": true, "retries": 3, "name": "feature", "database": {"host": "db"}}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	clearFeatureEnv()
+	defer clearFeatureEnv()
+
+	// Without environment variables, the file overrides the struct defaults
+	actual := featureConfig{Retries: 1, Realms: []string{"es"}}
+	actual.Database.Port = 5432
+	if err := GetConfig(file, &actual); err != nil {
+		t.Fatalf("Error getting config. %s", err)
+	}
+	expected := featureConfig{Enabled: true, Retries: 3, Name: "feature", Timeout: 5 * time.Second, Realms: []string{"es"}}
+	expected.Database.Host = "db"
+	expected.Database.Port = 5432
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Invalid config. Actual: %+v. Expected: %+v", actual, expected)
+	}
+
+	// Environment variables with zero values override the file
+	os.Setenv("FEATURE_ENABLED", "false")
+	os.Setenv("FEATURE_RETRIES", "0")
+	os.Setenv("FEATURE_NAME", "")
+	os.Setenv("FEATURE_TIMEOUT", "1m")
+	os.Setenv("FEATURE_REALMS", "es;uk")
+	os.Setenv("FEATURE_DB_HOST", "")
+	actual = featureConfig{}
+	if err := GetConfig(file, &actual); err != nil {
+		t.Fatalf("Error getting config. %s", err)
+	}
+	expected = featureConfig{Timeout: time.Minute, Realms: []string{"es", "uk"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Invalid config. Actual: %+v. Expected: %+v", actual, expected)
+	}
+}
+
+// TestGetConfigEnvDefaultBelowFile pins the precedence of envDefault: a value in the configuration file overrides
+// it (in previous versions, envDefault overrode the file). See CHANGELOG.md.
+func TestGetConfigEnvDefaultBelowFile(t *testing.T) {
+	file := writeTestFile(t, "config.json", `{"timeout": 2000000000}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	clearFeatureEnv()
+	defer clearFeatureEnv()
+	var actual featureConfig
+	if err := GetConfig(file, &actual); err != nil {
+		t.Fatalf("Error getting config. %s", err)
+	}
+	if actual.Timeout != 2*time.Second {
+		t.Errorf("Invalid timeout. Actual: %s. Expected: 2s", actual.Timeout)
+	}
+
+	// envDefault overrides the struct default if the file does not set the value
+	empty := writeTestFile(t, "config.json", `{}`)
+	defer os.RemoveAll(filepath.Dir(empty))
+	actual = featureConfig{Timeout: time.Second}
+	if err := GetConfig(empty, &actual); err != nil {
+		t.Fatalf("Error getting config. %s", err)
+	}
+	if actual.Timeout != 5*time.Second {
+		t.Errorf("Invalid timeout. Actual: %s. Expected: 5s", actual.Timeout)
+	}
+
+	// The environment variable overrides the file
+	os.Setenv("FEATURE_TIMEOUT", "1m")
+	actual = featureConfig{}
+	if err := GetConfig(file, &actual); err != nil {
+		t.Fatalf("Error getting config. %s", err)
+	}
+	if actual.Timeout != time.Minute {
+		t.Errorf("Invalid timeout. Actual: %s. Expected: 1m", actual.Timeout)
+	}
+}
+
+func TestGetConfigEnvErrors(t *testing.T) {
+	type requiredConfig struct {
+		Address string `json:"address" env:"TEST_REQUIRED_ADDRESS,required"`
+		Port    int    `json:"port" env:"TEST_REQUIRED_PORT"`
+	}
+	os.Unsetenv("TEST_REQUIRED_ADDRESS")
+	os.Setenv("TEST_REQUIRED_PORT", "http")
+	defer os.Unsetenv("TEST_REQUIRED_PORT")
+	var actual requiredConfig
+	err := GetConfig("testdata/config.json", &actual)
+	expected := `Error processing environment variables. required environment variable "TEST_REQUIRED_ADDRESS" is not set. ` +
+		`invalid value for environment variable "TEST_REQUIRED_PORT": strconv.ParseInt: parsing "http": invalid syntax`
+	if err == nil || err.Error() != expected {
+		t.Errorf("Invalid error. Actual: %v. Expected: %s", err, expected)
+	}
+}
+
+func TestGetConfigNotStruct(t *testing.T) {
+	var actual config
+	if err := GetConfig("testdata/config.json", actual); err == nil {
+		t.Errorf("Expected error getting config into a non-pointer")
+	}
+}
+
+func TestLoadEnvPrefix(t *testing.T) {
+	type poolConfig struct {
+		MaxSize int `json:"maxSize"`
+	}
+	type prefixConfig struct {
+		Address  string `json:"address" env:"ADDRESS"`
+		LogLevel string `json:"logLevel"`
+		Database struct {
+			URL  string     `json:"url"`
+			Pool poolConfig `json:"pool"`
+		} `json:"database"`
+		Realms []string          `json:"realms"`
+		Labels map[string]string `json:"labels"`
+	}
+	env := map[string]string{
+		"ADDRESS":                      ":8080",
+		"USERS_ADDRESS":                ":9090",
+		"USERS_LOG_LEVEL":              "DEBUG",
+		"USERS_DATABASE_URL":           "postgres://db",
+		"USERS_DATABASE_POOL_MAX_SIZE": "10",
+		"USERS_REALMS":                 "es,uk",
+		"USERS_LABELS":                 "team=users,tier=backend",
+	}
+	for name, value := range env {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+	loader := NewConfigLoader("testdata/config.json")
+	loader.SetEnvPrefix("USERS_")
+	var actual prefixConfig
+	if err := loader.Load(&actual); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+	expected := prefixConfig{
+		Address:  ":8080",
+		LogLevel: "DEBUG",
+		Realms:   []string{"es", "uk"},
+		Labels:   map[string]string{"team": "users", "tier": "backend"},
+	}
+	expected.Database.URL = "postgres://db"
+	expected.Database.Pool.MaxSize = 10
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Invalid config. Actual: %+v. Expected: %+v", actual, expected)
+	}
+	if source := loader.GetProvenance().Get("database.pool.maxSize"); source.String() != "env:USERS_DATABASE_POOL_MAX_SIZE" {
+		t.Errorf("Invalid source. Actual: %s", source)
+	}
+}
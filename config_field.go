@@ -0,0 +1,247 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configField is a leaf field of a configuration struct.
+// The path is built with the json names of the field and its parents (e.g. database.pool.maxSize).
+type configField struct {
+	path  string
+	index []int
+	field reflect.StructField
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// getConfigFields returns the leaf fields of a configuration struct type.
+// Nested structs are expanded unless they are bound to an environment variable or they
+// unmarshal themselves (e.g. time.Time).
+func getConfigFields(t reflect.Type) []configField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return appendConfigFields(nil, t, "", nil, map[reflect.Type]bool{})
+}
+
+func appendConfigFields(fields []configField, t reflect.Type, path string, index []int, visited map[reflect.Type]bool) []configField {
+	visited[t] = true
+	defer delete(visited, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		name, ok := configFieldName(field)
+		if !ok {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+		fieldPath := joinConfigPath(path, name)
+		if field.Anonymous && name == "" {
+			fieldPath = path
+		}
+		if isConfigStruct(field) && !visited[derefType(field.Type)] {
+			fields = appendConfigFields(fields, derefType(field.Type), fieldPath, fieldIndex, visited)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		fields = append(fields, configField{path: fieldPath, index: fieldIndex, field: field})
+	}
+	return fields
+}
+
+// configFieldName returns the json name of a field. Embedded structs without json name return an empty name
+// because their fields are promoted. Fields ignored by json are only considered if bound to an environment
+// variable (with the Go field name).
+func configFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	name := strings.Split(tag, ",")[0]
+	if tag == "-" {
+		if field.Tag.Get("env") == "" {
+			return "", false
+		}
+		return field.Name, true
+	}
+	if name != "" {
+		return name, true
+	}
+	if field.Anonymous && derefType(field.Type).Kind() == reflect.Struct {
+		return "", true
+	}
+	return field.Name, true
+}
+
+// isConfigStruct checks if a field is a nested struct whose fields are part of the configuration.
+// The structs decoded as text (e.g. url.URL) are values.
+func isConfigStruct(field reflect.StructField) bool {
+	t := derefType(field.Type)
+	if t.Kind() != reflect.Struct || field.Tag.Get("env") != "" || isTextConfigType(t) {
+		return false
+	}
+	ptr := reflect.PtrTo(t)
+	return !ptr.Implements(textUnmarshalerType) && !ptr.Implements(jsonUnmarshalerType)
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// configFieldValue returns the value of a field from the root struct value. If alloc is true, nil pointers
+// to nested structs are allocated; otherwise, it returns false if a nil pointer is found.
+func configFieldValue(root reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	v := root
+	for _, i := range index {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
+
+// setConfigValue parses a string and sets the value. Slices are split with the separator (comma by default),
+// and maps are lists of key=value items split with the separator.
+func setConfigValue(v reflect.Value, s string, separator string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setConfigValue(v.Elem(), s, separator)
+	}
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case urlType:
+		u, err := url.Parse(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(*u))
+		return nil
+	case regexpType:
+		r, err := regexp.Compile(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(r).Elem())
+		return nil
+	}
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if separator == "" {
+			separator = ","
+		}
+		items := strings.Split(s, separator)
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setConfigValue(slice.Index(i), item, separator); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		if separator == "" {
+			separator = ","
+		}
+		m := reflect.MakeMap(v.Type())
+		for _, item := range strings.Split(s, separator) {
+			kv := strings.SplitN(item, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid map item %q (expected key=value)", item)
+			}
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := setConfigValue(key, kv[0], separator); err != nil {
+				return err
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := setConfigValue(value, kv[1], separator); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("type %s is not supported", v.Type())
+	}
+	return nil
+}
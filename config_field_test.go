@@ -0,0 +1,129 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type embeddedConfig struct {
+	Service string `json:"service"`
+}
+
+type fieldsConfig struct {
+	embeddedConfig
+	Address  string `json:"address,omitempty"`
+	Ignored  string `json:"-"`
+	Secret   string `json:"-" env:"SECRET"`
+	internal string
+	Database *struct {
+		Host string `json:"host"`
+		Pool struct {
+			MaxSize int `json:"maxSize"`
+		} `json:"pool"`
+	} `json:"database"`
+	Started time.Time         `json:"started"`
+	Labels  map[string]string `json:"labels"`
+	Next    *fieldsConfig     `json:"next"`
+}
+
+func TestGetConfigFields(t *testing.T) {
+	var paths []string
+	for _, field := range getConfigFields(reflect.TypeOf(&fieldsConfig{})) {
+		paths = append(paths, field.path)
+	}
+	expected := []string{"service", "address", "Secret", "database.host", "database.pool.maxSize", "started", "labels", "next"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Invalid config fields. Actual: %v. Expected: %v", paths, expected)
+	}
+}
+
+func TestConfigFieldValue(t *testing.T) {
+	var cfg fieldsConfig
+	root := reflect.ValueOf(&cfg).Elem()
+	field := getConfigFields(root.Type())[3]
+	if _, ok := configFieldValue(root, field.index, false); ok {
+		t.Errorf("Expected nil pointer without allocation")
+	}
+	value, ok := configFieldValue(root, field.index, true)
+	if !ok || cfg.Database == nil {
+		t.Fatalf("Expected allocated pointer")
+	}
+	value.SetString("db")
+	if cfg.Database.Host != "db" {
+		t.Errorf("Invalid field value. Actual: %s", cfg.Database.Host)
+	}
+}
+
+func TestSetConfigValue(t *testing.T) {
+	var (
+		s   string
+		b   bool
+		i   int8
+		u   uint
+		f   float64
+		d   time.Duration
+		p   *int
+		ss  []string
+		is  []int
+		tm  time.Time
+		m   map[string]int
+		bad chan int
+	)
+	tests := []struct {
+		value     interface{}
+		s         string
+		separator string
+		expected  interface{}
+		fails     bool
+	}{
+		{&s, "text", "", "text", false},
+		{&b, "true", "", true, false},
+		{&b, "yes", "", false, true},
+		{&i, "-12", "", int8(-12), false},
+		{&i, "300", "", int8(0), true},
+		{&u, "12", "", uint(12), false},
+		{&f, "1.5", "", 1.5, false},
+		{&d, "1m30s", "", 90 * time.Second, false},
+		{&ss, "a,b", "", []string{"a", "b"}, false},
+		{&ss, "a;b", ";", []string{"a", "b"}, false},
+		{&is, "1,2", "", []int{1, 2}, false},
+		{&tm, "2018-01-02T03:04:05Z", "", time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{&m, "a=1,b=2", "", map[string]int{"a": 1, "b": 2}, false},
+		{&m, "a=1;b=2", ";", map[string]int{"a": 1, "b": 2}, false},
+		{&m, "a=1,b", "", map[string]int(nil), true},
+		{&m, "a=x", "", map[string]int(nil), true},
+		{&bad, "1", "", nil, true},
+	}
+	for _, test := range tests {
+		value := reflect.ValueOf(test.value).Elem()
+		err := setConfigValue(value, test.s, test.separator)
+		if (err != nil) != test.fails {
+			t.Errorf("Invalid error parsing %s into %s. %v", test.s, value.Type(), err)
+			continue
+		}
+		if !test.fails && !reflect.DeepEqual(value.Interface(), test.expected) {
+			t.Errorf("Invalid value parsing %s. Actual: %v. Expected: %v", test.s, value.Interface(), test.expected)
+		}
+	}
+	if err := setConfigValue(reflect.ValueOf(&p).Elem(), "7", ""); err != nil || p == nil || *p != 7 {
+		t.Errorf("Invalid pointer value. %v", err)
+	}
+}
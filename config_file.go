@@ -0,0 +1,568 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileError is an error found in a configuration file.
+// It identifies the key (if known) and the position (line and column) of the error in the file.
+type ConfigFileError struct {
+	File   string
+	Key    string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ConfigFileError) Error() string {
+	var buf bytes.Buffer
+	buf.WriteString(e.File)
+	if e.Line > 0 {
+		buf.WriteString(":" + strconv.Itoa(e.Line))
+		if e.Column > 0 {
+			buf.WriteString(":" + strconv.Itoa(e.Column))
+		}
+	}
+	buf.WriteString(": ")
+	if e.Key != "" {
+		buf.WriteString("key " + strconv.Quote(e.Key) + ": ")
+	}
+	buf.WriteString(e.Err.Error())
+	return buf.String()
+}
+
+// filePosition is the position (line and column, starting at 1) of an element in a file.
+type filePosition struct {
+	Line   int
+	Column int
+}
+
+// configDocument is a configuration file decoded as a generic JSON document.
+// It keeps the position of every object key (indexed by its path) to report errors.
+// When several documents are merged, it also keeps the file that set each key.
+type configDocument struct {
+	file      string
+	values    map[string]interface{}
+	positions map[string]filePosition
+	files     map[string]string
+}
+
+// configDecoder decodes the content of a configuration file into a configDocument.
+type configDecoder func(file string, data []byte) (*configDocument, error)
+
+// configDecoders maps the extension of a configuration file with its decoder.
+// Files with other extensions are decoded as JSON.
+var configDecoders = map[string]configDecoder{
+	".json": decodeJSONConfig,
+	".yaml": decodeYAMLConfig,
+	".yml":  decodeYAMLConfig,
+	".toml": decodeTOMLConfig,
+}
+
+// readConfigDocument reads a configuration file selecting the decoder according to the file extension.
+func readConfigDocument(configFile string) (*configDocument, error) {
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	decode, ok := configDecoders[strings.ToLower(filepath.Ext(configFile))]
+	if !ok {
+		decode = decodeJSONConfig
+	}
+	return decode(configFile, data)
+}
+
+// unmarshal the document into the configuration struct (using the json struct tags).
+// Type errors are reported with the key and its position in the file.
+// Values of some types (e.g. time.Duration) are decoded from strings with the same syntax as the environment
+// variables (see isTextConfigType).
+func (d *configDocument) unmarshal(config interface{}) error {
+	t := reflect.TypeOf(config)
+	data, err := json.Marshal(stripTextValues(d.values, t))
+	if err != nil {
+		return &ConfigFileError{File: d.file, Err: err}
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+			pos := d.positions[typeErr.Field]
+			return &ConfigFileError{
+				File:   d.keyFile(typeErr.Field),
+				Key:    typeErr.Field,
+				Line:   pos.Line,
+				Column: pos.Column,
+				Err:    fmt.Errorf("cannot use %s value as %s", typeErr.Value, typeErr.Type),
+			}
+		}
+		return &ConfigFileError{File: d.file, Err: err}
+	}
+	if t.Kind() == reflect.Ptr {
+		if path, err := setTextValues(d.values, reflect.ValueOf(config).Elem(), ""); err != nil {
+			pos := d.keyPosition(path)
+			return &ConfigFileError{File: d.keyFile(path), Key: path, Line: pos.Line, Column: pos.Column, Err: err}
+		}
+	}
+	return nil
+}
+
+// mapStrings replaces every string value of the document (including the items of arrays) with the result of
+// a function. The keys are processed in alphabetical order to report always the same error. Errors are
+// reported with the key and its position.
+func (d *configDocument) mapStrings(fn func(path, s string) (string, error)) error {
+	_, err := d.mapStringValue(d.values, "", fn)
+	return err
+}
+
+func (d *configDocument) mapStringValue(value interface{}, path string, fn func(path, s string) (string, error)) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		s, err := fn(path, v)
+		if err != nil {
+			pos := d.keyPosition(path)
+			return nil, &ConfigFileError{File: d.keyFile(path), Key: path, Line: pos.Line, Column: pos.Column, Err: err}
+		}
+		return s, nil
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			item, err := d.mapStringValue(v[key], joinConfigPath(path, key), fn)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = item
+		}
+	case []interface{}:
+		for i, item := range v {
+			item, err := d.mapStringValue(item, joinConfigPath(path, strconv.Itoa(i)), fn)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = item
+		}
+	}
+	return value, nil
+}
+
+// keyPosition returns the position of a key (or its closest parent key, e.g. for array items).
+func (d *configDocument) keyPosition(path string) filePosition {
+	for {
+		if pos, ok := d.positions[path]; ok {
+			return pos
+		}
+		i := strings.LastIndexByte(path, '.')
+		if i < 0 {
+			return filePosition{}
+		}
+		path = path[:i]
+	}
+}
+
+// keyFile returns the file that set a key (or its closest parent object).
+func (d *configDocument) keyFile(path string) string {
+	for {
+		if file, ok := d.files[path]; ok {
+			return file
+		}
+		i := strings.LastIndexByte(path, '.')
+		if i < 0 {
+			return d.file
+		}
+		path = path[:i]
+	}
+}
+
+// merge overlays another document on top of this one. Objects are merged recursively. Any other value
+// (including arrays) replaces the previous one.
+func (d *configDocument) merge(overlay *configDocument) {
+	if d.files == nil {
+		d.files = make(map[string]string)
+	}
+	d.mergeValues(d.values, overlay.values, overlay, "")
+}
+
+func (d *configDocument) mergeValues(dst, src map[string]interface{}, overlay *configDocument, path string) {
+	for key, value := range src {
+		keyPath := joinConfigPath(path, key)
+		srcObject, srcIsObject := value.(map[string]interface{})
+		dstObject, dstIsObject := dst[key].(map[string]interface{})
+		if srcIsObject && dstIsObject {
+			d.mergeValues(dstObject, srcObject, overlay, keyPath)
+			continue
+		}
+		// Replace the whole value, including the origin of its nested keys
+		prefix := keyPath + "."
+		for p := range d.positions {
+			if strings.HasPrefix(p, prefix) {
+				delete(d.positions, p)
+				delete(d.files, p)
+			}
+		}
+		for p := range overlay.positions {
+			if strings.HasPrefix(p, prefix) {
+				d.setKeyOrigin(p, overlay)
+			}
+		}
+		d.setKeyOrigin(keyPath, overlay)
+		dst[key] = value
+	}
+}
+
+func (d *configDocument) setKeyOrigin(path string, overlay *configDocument) {
+	if pos, ok := overlay.positions[path]; ok {
+		d.positions[path] = pos
+	} else {
+		delete(d.positions, path)
+	}
+	d.files[path] = overlay.keyFile(path)
+}
+
+// sortedKeys returns the keys of an object in alphabetical order.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// offsetPosition converts a byte offset into a position (line and column).
+func offsetPosition(data []byte, offset int) filePosition {
+	if offset > len(data) {
+		offset = len(data)
+	} else if offset < 0 {
+		offset = 0
+	}
+	line := 1 + bytes.Count(data[:offset], []byte{'\n'})
+	column := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return filePosition{Line: line, Column: column}
+}
+
+func decodeJSONConfig(file string, data []byte) (*configDocument, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var values map[string]interface{}
+	if err := decoder.Decode(&values); err != nil {
+		fileErr := &ConfigFileError{File: file, Err: err}
+		switch e := err.(type) {
+		case *json.SyntaxError:
+			// The offset of a syntax error is just after the invalid character
+			pos := offsetPosition(data, int(e.Offset)-1)
+			fileErr.Line, fileErr.Column = pos.Line, pos.Column
+		case *json.UnmarshalTypeError:
+			pos := offsetPosition(data, int(e.Offset))
+			fileErr.Line, fileErr.Column = pos.Line, pos.Column
+			fileErr.Err = errors.New("configuration must be a JSON object")
+		}
+		return nil, fileErr
+	}
+	// Reject any data after the JSON object (as json.Unmarshal does)
+	if _, err := decoder.Token(); err != io.EOF {
+		offset := skipJSONSeparators(data, int(decoder.InputOffset()))
+		// If there are only separators after the JSON object, the decoder error is reported at the end of the file
+		if offset < len(data) {
+			err = fmt.Errorf("invalid character %q after top-level value", data[offset])
+		} else if err == nil {
+			err = errors.New("unexpected data after top-level value")
+		}
+		pos := offsetPosition(data, offset)
+		return nil, &ConfigFileError{File: file, Line: pos.Line, Column: pos.Column, Err: err}
+	}
+	positions := make(map[string]filePosition)
+	indexJSONPositions(json.NewDecoder(bytes.NewReader(data)), data, "", true, positions)
+	return &configDocument{file: file, values: values, positions: positions}, nil
+}
+
+// indexJSONPositions walks a JSON value token by token to register the position of every object key.
+// Keys inside arrays are not indexed because arrays are handled as a single value.
+func indexJSONPositions(decoder *json.Decoder, data []byte, path string, index bool, positions map[string]filePosition) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		for decoder.More() {
+			offset := skipJSONSeparators(data, int(decoder.InputOffset()))
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			keyPath := joinConfigPath(path, fmt.Sprint(token))
+			if index {
+				positions[keyPath] = offsetPosition(data, offset)
+			}
+			if err := indexJSONPositions(decoder, data, keyPath, index, positions); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+	case json.Delim('['):
+		for decoder.More() {
+			if err := indexJSONPositions(decoder, data, path, false, positions); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+	}
+	return err
+}
+
+func skipJSONSeparators(data []byte, offset int) int {
+	for offset < len(data) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+var yamlLineRegexp = regexp.MustCompile(`line (\d+)`)
+
+func decodeYAMLConfig(file string, data []byte) (*configDocument, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		fileErr := &ConfigFileError{File: file, Err: err}
+		if match := yamlLineRegexp.FindStringSubmatch(err.Error()); match != nil {
+			fileErr.Line, _ = strconv.Atoi(match[1])
+		}
+		return nil, fileErr
+	}
+	doc := &configDocument{file: file, values: map[string]interface{}{}, positions: map[string]filePosition{}}
+	if len(root.Content) == 0 {
+		return doc, nil
+	}
+	node := root.Content[0]
+	if node.Kind != yaml.MappingNode {
+		return nil, &ConfigFileError{File: file, Line: node.Line, Column: node.Column, Err: errors.New("configuration must be a YAML mapping")}
+	}
+	values, err := yamlNodeValue(file, node, "", true, doc.positions)
+	if err != nil {
+		return nil, err
+	}
+	doc.values = values.(map[string]interface{})
+	return doc, nil
+}
+
+// yamlNodeValue converts a YAML node into a generic JSON value registering the position of the mapping keys.
+func yamlNodeValue(file string, node *yaml.Node, path string, index bool, positions map[string]filePosition) (interface{}, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return yamlNodeValue(file, node.Alias, path, false, positions)
+	case yaml.MappingNode:
+		values := make(map[string]interface{})
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			if keyNode.Tag == "!!merge" {
+				merged, err := yamlNodeValue(file, valueNode, path, false, positions)
+				if err != nil {
+					return nil, err
+				}
+				for _, m := range yamlMergeMappings(merged) {
+					for k, v := range m {
+						if _, ok := values[k]; !ok {
+							values[k] = v
+						}
+					}
+				}
+				continue
+			}
+			keyPath := joinConfigPath(path, keyNode.Value)
+			if index {
+				positions[keyPath] = filePosition{Line: keyNode.Line, Column: keyNode.Column}
+			}
+			value, err := yamlNodeValue(file, valueNode, keyPath, index, positions)
+			if err != nil {
+				return nil, err
+			}
+			values[keyNode.Value] = value
+		}
+		return values, nil
+	case yaml.SequenceNode:
+		values := make([]interface{}, 0, len(node.Content))
+		for _, itemNode := range node.Content {
+			value, err := yamlNodeValue(file, itemNode, path, false, positions)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	default:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, &ConfigFileError{File: file, Key: path, Line: node.Line, Column: node.Column, Err: err}
+		}
+		return value, nil
+	}
+}
+
+// yamlMergeMappings returns the mappings referenced by a YAML merge key (<<), which may be a mapping or a list of them.
+func yamlMergeMappings(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []interface{}:
+		var mappings []map[string]interface{}
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				mappings = append(mappings, m)
+			}
+		}
+		return mappings
+	}
+	return nil
+}
+
+func decodeTOMLConfig(file string, data []byte) (*configDocument, error) {
+	values := make(map[string]interface{})
+	if _, err := toml.Decode(string(data), &values); err != nil {
+		fileErr := &ConfigFileError{File: file, Err: err}
+		if parseErr, ok := err.(toml.ParseError); ok {
+			pos := offsetPosition(data, parseErr.Position.Start)
+			fileErr.Line, fileErr.Column = pos.Line, pos.Column
+			if parseErr.Message != "" {
+				fileErr.Err = errors.New(parseErr.Message)
+			}
+		}
+		return nil, fileErr
+	}
+	return &configDocument{file: file, values: values, positions: indexTOMLPositions(data)}, nil
+}
+
+// indexTOMLPositions scans the lines of a valid TOML document to register the position of the table headers
+// and the keys. Keys inside arrays of tables and inline tables are not indexed (their errors are reported with
+// the position of the closest parent key).
+func indexTOMLPositions(data []byte) map[string]filePosition {
+	positions := make(map[string]filePosition)
+	table := ""
+	index := true
+	multiline := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		column := len(line) - len(trimmed) + 1
+		if multiline != "" {
+			// Skip the lines of a multi-line string
+			if strings.Count(line, multiline)%2 == 1 {
+				multiline = ""
+			}
+			continue
+		}
+		switch {
+		case trimmed == "" || trimmed[0] == '#':
+		case strings.HasPrefix(trimmed, "[["):
+			table = strings.Join(splitTOMLKey(strings.SplitN(trimmed[2:], "]]", 2)[0]), ".")
+			index = false
+		case trimmed[0] == '[':
+			table = strings.Join(splitTOMLKey(strings.SplitN(trimmed[1:], "]", 2)[0]), ".")
+			index = true
+			positions[table] = filePosition{Line: i + 1, Column: column}
+		default:
+			eq := tomlKeyEnd(trimmed)
+			if eq < 0 {
+				continue
+			}
+			if index {
+				path := table
+				for _, key := range splitTOMLKey(trimmed[:eq]) {
+					path = joinConfigPath(path, key)
+					positions[path] = filePosition{Line: i + 1, Column: column}
+				}
+			}
+			value := trimmed[eq+1:]
+			for _, delim := range []string{`"""`, "'''"} {
+				if strings.Count(value, delim)%2 == 1 {
+					multiline = delim
+				}
+			}
+		}
+	}
+	return positions
+}
+
+// tomlKeyEnd returns the index of the equal sign after the key of a key/value line (or -1).
+func tomlKeyEnd(line string) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return i
+		}
+	}
+	return -1
+}
+
+// splitTOMLKey splits a dotted TOML key (e.g. database."pool".size) into its parts, unquoting them.
+func splitTOMLKey(key string) []string {
+	var parts []string
+	var part strings.Builder
+	var quote byte
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' && i+1 < len(key) {
+				i++
+				part.WriteByte(key[i])
+			} else if c == quote {
+				quote = 0
+			} else {
+				part.WriteByte(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '.':
+			parts = append(parts, part.String())
+			part.Reset()
+		case c != ' ' && c != '\t':
+			part.WriteByte(c)
+		}
+	}
+	return append(parts, part.String())
+}
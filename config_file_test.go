@@ -0,0 +1,187 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name string, content string) string {
+	dir, err := ioutil.TempDir("", "govice")
+	if err != nil {
+		t.Fatalf("Error creating temporary directory. %s", err)
+	}
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("Error writing temporary file. %s", err)
+	}
+	return file
+}
+
+func loadConfigFile(configFile string, config interface{}) error {
+	doc, err := readConfigDocument(configFile)
+	if err != nil {
+		return err
+	}
+	return doc.unmarshal(config)
+}
+
+func TestLoadConfigFileFormats(t *testing.T) {
+	expected := config{Address: ":80", BasePath: "/users", LogLevel: "INFO", Realm: "es"}
+	for _, file := range []string{"testdata/config.json", "testdata/config.yaml", "testdata/config.toml"} {
+		var actual config
+		if err := loadConfigFile(file, &actual); err != nil {
+			t.Errorf("Error loading config file %s. %s", file, err)
+		}
+		if actual != expected {
+			t.Errorf("Invalid config from %s. Actual: %+v. Expected: %+v.", file, actual, expected)
+		}
+	}
+}
+
+func TestLoadConfigFileNested(t *testing.T) {
+	type nestedConfig struct {
+		Database struct {
+			Host string `json:"host"`
+			Pool struct {
+				MaxSize int `json:"maxSize"`
+			} `json:"pool"`
+		} `json:"database"`
+		Realms []string `json:"realms"`
+	}
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.json", `{"database": {"host": "db", "pool": {"maxSize": 10}}, "realms": ["es", "uk"]}`},
+		{"config.yml", "database:\n  host: db\n  pool:\n    maxSize: 10\nrealms: [es, uk]\n"},
+		{"config.toml", "realms = [\"es\", \"uk\"]\n[database]\nhost = \"db\"\n[database.pool]\nmaxSize = 10\n"},
+	}
+	for _, test := range tests {
+		file := writeTestFile(t, test.name, test.content)
+		defer os.RemoveAll(filepath.Dir(file))
+		var actual nestedConfig
+		if err := loadConfigFile(file, &actual); err != nil {
+			t.Errorf("Error loading config file %s. %s", test.name, err)
+			continue
+		}
+		if actual.Database.Host != "db" || actual.Database.Pool.MaxSize != 10 || len(actual.Realms) != 2 {
+			t.Errorf("Invalid config from %s. Actual: %+v.", test.name, actual)
+		}
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	type typedConfig struct {
+		Address string `json:"address"`
+		Port    int    `json:"port"`
+	}
+	tests := []struct {
+		name     string
+		content  string
+		expected ConfigFileError
+	}{
+		{"config.json", "{\n  \"address\": \":80\",\n  \"port\": \"80\"\n}\n", ConfigFileError{Key: "port", Line: 3, Column: 3}},
+		{"config.json", "{\n  \"address\": \":80\"\n  \"port\": 80\n}\n", ConfigFileError{Line: 3, Column: 3}},
+		{"config.yaml", "address: \":80\"\nport: http\n", ConfigFileError{Key: "port", Line: 2, Column: 1}},
+		{"config.yaml", "address: \":80\"\nport: 80\n  realm: es\n", ConfigFileError{Line: 3}},
+		{"config.toml", "address = \":80\"\nport = \n", ConfigFileError{Line: 2, Column: 8}},
+		{"config.toml", "address = \":80\"\n  port = \"80\"\n", ConfigFileError{Key: "port", Line: 2, Column: 3}},
+		{"config.json", "{\"address\": \":80\"}\n garbage\n", ConfigFileError{Line: 2, Column: 2}},
+		{"config.json", "{\"address\":\":80\"},", ConfigFileError{Line: 1, Column: 19}},
+		{"config.json", "{\"address\":\":80\"}\n:", ConfigFileError{Line: 2, Column: 2}},
+	}
+	for _, test := range tests {
+		file := writeTestFile(t, test.name, test.content)
+		defer os.RemoveAll(filepath.Dir(file))
+		var actual typedConfig
+		err := loadConfigFile(file, &actual)
+		fileErr, ok := err.(*ConfigFileError)
+		if !ok {
+			t.Errorf("Invalid error loading %s. Actual: %v", test.content, err)
+			continue
+		}
+		if fileErr.File != file || fileErr.Key != test.expected.Key || fileErr.Line != test.expected.Line || fileErr.Column != test.expected.Column {
+			t.Errorf("Invalid error loading %s. Actual: %s. Expected: %+v", test.content, fileErr, test.expected)
+		}
+	}
+}
+
+func TestConfigFileErrorMessage(t *testing.T) {
+	err := &ConfigFileError{File: "config.yaml", Key: "port", Line: 2, Column: 1, Err: os.ErrInvalid}
+	if expected := `config.yaml:2:1: key "port": invalid argument`; err.Error() != expected {
+		t.Errorf("Invalid error message. Actual: %s. Expected: %s", err.Error(), expected)
+	}
+}
+
+func TestConfigDocumentMerge(t *testing.T) {
+	base := writeTestFile(t, "config.json", `{"address": ":80", "database": {"host": "db", "pool": {"maxSize": 10}}, "realms": ["es"]}`)
+	defer os.RemoveAll(filepath.Dir(base))
+	overlay := writeTestFile(t, "config.yaml", "database:\n  pool:\n    maxSize: 20\nrealms: [uk]\n")
+	defer os.RemoveAll(filepath.Dir(overlay))
+	doc, err := readConfigDocument(base)
+	if err != nil {
+		t.Fatalf("Error reading base document. %s", err)
+	}
+	overlayDoc, err := readConfigDocument(overlay)
+	if err != nil {
+		t.Fatalf("Error reading overlay document. %s", err)
+	}
+	doc.merge(overlayDoc)
+	expected := map[string]interface{}{
+		"address":  ":80",
+		"database": map[string]interface{}{"host": "db", "pool": map[string]interface{}{"maxSize": 20}},
+		"realms":   []interface{}{"uk"},
+	}
+	if !reflect.DeepEqual(doc.values, expected) {
+		t.Errorf("Invalid merged document. Actual: %v. Expected: %v", doc.values, expected)
+	}
+	files := map[string]string{
+		"address":               base,
+		"database.host":         base,
+		"database.pool.maxSize": overlay,
+		"realms":                overlay,
+	}
+	for key, expected := range files {
+		if actual := doc.keyFile(key); actual != expected {
+			t.Errorf("Invalid file for key %s. Actual: %s. Expected: %s", key, actual, expected)
+		}
+	}
+	if pos := doc.positions["database.pool.maxSize"]; pos.Line != 3 || pos.Column != 5 {
+		t.Errorf("Invalid position for merged key. Actual: %+v", pos)
+	}
+}
+
+func TestIndexTOMLPositions(t *testing.T) {
+	content := "# Configuration\naddress = \":80\"\ndescription = \"\"\"\nfake = key\n\"\"\"\n[database]\n  \"host.name\" = \"db\"\n  pool.maxSize = 10\n[[realms]]\nname = \"es\"\n"
+	expected := map[string]filePosition{
+		"address":               {Line: 2, Column: 1},
+		"description":           {Line: 3, Column: 1},
+		"database":              {Line: 6, Column: 1},
+		"database.host.name":    {Line: 7, Column: 3},
+		"database.pool":         {Line: 8, Column: 3},
+		"database.pool.maxSize": {Line: 8, Column: 3},
+	}
+	if actual := indexTOMLPositions([]byte(content)); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Invalid TOML positions. Actual: %v. Expected: %v", actual, expected)
+	}
+}
@@ -0,0 +1,187 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// ConfigFlagSet is the set of command-line flags bound to the fields of a configuration struct.
+type ConfigFlagSet struct {
+	configType reflect.Type
+	flags      []*configFlag
+}
+
+// configFlag is a flag.Value that keeps the raw value of a flag until the configuration is loaded.
+type configFlag struct {
+	name  string
+	field configField
+	def   string
+	value string
+	set   bool
+}
+
+func (f *configFlag) String() string {
+	if f.set {
+		return f.value
+	}
+	return f.def
+}
+
+// Set checks that the value is valid for the field (so errors are reported when parsing the flags) and stores it.
+func (f *configFlag) Set(s string) error {
+	if s != "" {
+		v := reflect.New(f.field.field.Type).Elem()
+		if err := setConfigValue(v, s, f.field.field.Tag.Get("envSeparator")); err != nil {
+			return err
+		}
+	}
+	f.value = s
+	f.set = true
+	return nil
+}
+
+// IsBoolFlag enables boolean flags without value (e.g. --debug).
+func (f *configFlag) IsBoolFlag() bool {
+	return derefType(f.field.field.Type).Kind() == reflect.Bool
+}
+
+// BindConfigFlags defines a command-line flag in fs for every field of the configuration struct config
+// (a pointer to a struct). The flag name is set with the flag struct tag or derived from the JSON path of
+// the field (e.g. log-level for logLevel or database.max-size for database.maxSize). Fields tagged with
+// flag:"-" and fields whose type cannot be parsed from a string (e.g. functions) are skipped. Secret fields are
+// also skipped unless they have an explicit flag tag, because the command line is visible to other processes.
+// The usage is set with the desc struct tag, and the default value is the current value of the field (redacted
+// for secrets).
+//
+// The flags must be set to the ConfigLoader with SetFlags. They have the highest precedence.
+func BindConfigFlags(fs *flag.FlagSet, config interface{}) (*ConfigFlagSet, error) {
+	root := reflect.ValueOf(config)
+	if root.Kind() != reflect.Ptr || root.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Error binding command-line flags. Expected a pointer to a struct")
+	}
+	root = root.Elem()
+	flags := &ConfigFlagSet{configType: root.Type()}
+	for _, f := range getConfigFields(root.Type()) {
+		name, ok := f.field.Tag.Lookup("flag")
+		if name == "-" || (!ok && isSecretField(f.field)) {
+			continue
+		}
+		if !isFlagType(f.field.Type) {
+			if ok {
+				return nil, fmt.Errorf("Error binding command-line flags. Type %s of flag %q is not supported", f.field.Type, name)
+			}
+			continue
+		}
+		if name == "" {
+			name = configFlagName(f.path)
+		}
+		if fs.Lookup(name) != nil {
+			return nil, fmt.Errorf("Error binding command-line flags. Flag %q already defined", name)
+		}
+		cf := &configFlag{name: name, field: f}
+		if value, ok := configFieldValue(root, f.index, false); ok {
+			cf.def = formatConfigValue(value, f.field.Tag.Get("envSeparator"))
+		}
+		if isSecretField(f.field) && cf.def != "" {
+			cf.def = RedactedValue
+		}
+		fs.Var(cf, name, f.field.Tag.Get("desc"))
+		flags.flags = append(flags.flags, cf)
+	}
+	return flags, nil
+}
+
+// applyFlags sets the fields whose flag is set in the command line.
+func (c *ConfigLoader) applyFlags(root reflect.Value) error {
+	if root.Type() != c.flags.configType {
+		return fmt.Errorf("flags bound to type %s", c.flags.configType)
+	}
+	for _, f := range c.flags.flags {
+		if !f.set {
+			continue
+		}
+		value, _ := configFieldValue(root, f.field.index, true)
+		if f.value == "" {
+			value.Set(reflect.Zero(value.Type()))
+		} else if err := setConfigValue(value, f.value, f.field.field.Tag.Get("envSeparator")); err != nil {
+			return fmt.Errorf("invalid value for flag %q: %s", f.name, err)
+		}
+		c.origins[f.field.path] = configOrigin{source: ConfigSourceFlag, name: f.name}
+	}
+	return nil
+}
+
+// isFlagType checks if a value of the type can be parsed from a string by setConfigValue.
+func isFlagType(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) || isTextConfigType(t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return isFlagType(t.Elem())
+	case reflect.Slice:
+		return isFlagItemType(t.Elem())
+	case reflect.Map:
+		return isFlagItemType(t.Key()) && isFlagItemType(t.Elem())
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isFlagItemType checks if the type of the items of a slice or map can be parsed from a string.
+// Nested slices and maps are not supported because they share the separator.
+func isFlagItemType(t reflect.Type) bool {
+	k := derefType(t).Kind()
+	return k != reflect.Slice && k != reflect.Map && isFlagType(t)
+}
+
+// configFlagName converts every key of a configuration path from camel case to kebab case
+// (e.g. database.maxSize to database.max-size).
+func configFlagName(path string) string {
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		keys[i] = kebabCase(key)
+	}
+	return strings.Join(keys, ".")
+}
+
+func kebabCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('-')
+			}
+		}
+		if r == '_' {
+			r = '-'
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
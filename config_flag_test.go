@@ -0,0 +1,185 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type flagDatabaseConfig struct {
+	MaxSize int `json:"maxSize"`
+}
+
+type flagConfig struct {
+	Address  string             `json:"address" env:"ADDRESS"`
+	BasePath string             `json:"basePath" env:"BASE_PATH"`
+	LogLevel string             `json:"logLevel" env:"LOG_LEVEL" flag:"level" desc:"Log level"`
+	Realm    string             `json:"realm" flag:"-"`
+	Debug    bool               `json:"debug"`
+	Timeout  time.Duration      `json:"timeout"`
+	Realms   []string           `json:"realms"`
+	Labels   map[string]string  `json:"labels"`
+	Database flagDatabaseConfig `json:"database"`
+}
+
+func TestBindConfigFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := flagConfig{Timeout: time.Second}
+	if _, err := BindConfigFlags(fs, &cfg); err != nil {
+		t.Fatalf("Error binding flags. %s", err)
+	}
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	expected := []string{"address", "base-path", "database.max-size", "debug", "labels", "level", "realms", "timeout"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Invalid flags. Actual: %v. Expected: %v", names, expected)
+	}
+	if f := fs.Lookup("timeout"); f.DefValue != "1s" {
+		t.Errorf("Invalid default value. Actual: %s", f.DefValue)
+	}
+	if f := fs.Lookup("level"); f.Usage != "Log level" {
+		t.Errorf("Invalid usage. Actual: %s", f.Usage)
+	}
+}
+
+func TestBindConfigFlagsSecrets(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := struct {
+		Password string `json:"password" secret:"true"`
+		Token    string `json:"token" secret:"true" flag:"token"`
+	}{Token: "token"}
+	if _, err := BindConfigFlags(fs, &cfg); err != nil {
+		t.Fatalf("Error binding flags. %s", err)
+	}
+	if fs.Lookup("password") != nil {
+		t.Errorf("Secret field without flag tag must not be bound")
+	}
+	if f := fs.Lookup("token"); f == nil || f.DefValue != RedactedValue {
+		t.Errorf("Secret field with flag tag must be bound with a redacted default. Actual: %+v", f)
+	}
+}
+
+func TestBindConfigFlagsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config interface{}
+	}{
+		{"not a pointer", flagConfig{}},
+		{"unsupported type", &struct {
+			Labels map[string][]string `json:"labels" flag:"labels"`
+		}{}},
+		{"duplicated flag", &struct {
+			Address string `json:"address"`
+			Addr    string `json:"addr" flag:"address"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			if _, err := BindConfigFlags(fs, tt.config); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
+
+func TestLoadFlags(t *testing.T) {
+	os.Setenv("ADDRESS", ":8080")
+	os.Setenv("LOG_LEVEL", "ERROR")
+	os.Unsetenv("BASE_PATH")
+	defer os.Unsetenv("ADDRESS")
+	defer os.Unsetenv("LOG_LEVEL")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var cfg flagConfig
+	flags, err := BindConfigFlags(fs, &cfg)
+	if err != nil {
+		t.Fatalf("Error binding flags. %s", err)
+	}
+	args := []string{"--address", ":9090", "--level=", "--debug", "--timeout", "5s", "--realms", "es,uk", "--labels", "a=1,b=2", "--database.max-size", "10"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Error parsing flags. %s", err)
+	}
+	loader := NewConfigLoader("testdata/config.json")
+	loader.SetFlags(flags)
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+	expected := flagConfig{
+		Address:  ":9090",
+		BasePath: "/users",
+		Realm:    "es",
+		Debug:    true,
+		Timeout:  5 * time.Second,
+		Realms:   []string{"es", "uk"},
+		Labels:   map[string]string{"a": "1", "b": "2"},
+		Database: flagDatabaseConfig{MaxSize: 10},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Invalid config. Actual: %+v. Expected: %+v", cfg, expected)
+	}
+	provenance := loader.GetProvenance()
+	if source := provenance.Get("address"); source.String() != "flag:address" {
+		t.Errorf("Invalid source for address. Actual: %s", source)
+	}
+	if source := provenance.Get("basePath"); source.Source != ConfigSourceFile {
+		t.Errorf("Invalid source for basePath. Actual: %s", source)
+	}
+
+	var other config
+	if err := loader.Load(&other); err == nil {
+		t.Errorf("Expected error loading a different type")
+	}
+}
+
+func TestParseInvalidFlag(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	if _, err := BindConfigFlags(fs, &flagConfig{}); err != nil {
+		t.Fatalf("Error binding flags. %s", err)
+	}
+	if err := fs.Parse([]string{"--timeout", "5"}); err == nil {
+		t.Errorf("Expected error parsing an invalid duration")
+	}
+}
+
+func TestConfigFlagName(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"address", "address"},
+		{"logLevel", "log-level"},
+		{"database.maxSize", "database.max-size"},
+		{"dbURLPath", "db-url-path"},
+		{"URL", "url"},
+		{"retry2Times", "retry2-times"},
+		{"base_path", "base-path"},
+	}
+	for _, tt := range tests {
+		if actual := configFlagName(tt.path); actual != tt.expected {
+			t.Errorf("Invalid flag name for %s. Actual: %s. Expected: %s", tt.path, actual, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// placeholderRegexp matches the placeholders ${VAR} and ${VAR:-default}, and the escape sequence $${.
+var placeholderRegexp = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// interpolate expands the placeholders in the string values of the document with the environment variables:
+//   - ${VAR} is replaced with the value of VAR. It is an error if VAR is not set.
+//   - ${VAR:-default} is replaced with the value of VAR, or with default if VAR is not set or empty.
+//   - $${ is replaced with ${ (to write a literal placeholder).
+func (d *configDocument) interpolate(lookupEnv func(string) (string, bool)) error {
+	return d.mapStrings(func(path, s string) (string, error) {
+		return interpolateString(s, lookupEnv)
+	})
+}
+
+func interpolateString(s string, lookupEnv func(string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var err error
+	s = placeholderRegexp.ReplaceAllStringFunc(s, func(placeholder string) string {
+		if placeholder == "$${" {
+			return "${"
+		}
+		match := placeholderRegexp.FindStringSubmatch(placeholder)
+		name, hasDefault := match[1], strings.Contains(placeholder, ":-")
+		value, ok := lookupEnv(name)
+		if hasDefault && value == "" {
+			return match[2]
+		}
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %q in placeholder %s is not set", name, placeholder)
+		}
+		return value
+	})
+	return s, err
+}
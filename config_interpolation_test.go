@@ -0,0 +1,119 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInterpolateString(t *testing.T) {
+	env := map[string]string{"DB_HOST": "db", "EMPTY": ""}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	tests := []struct {
+		value    string
+		expected string
+		valid    bool
+	}{
+		{"http://${DB_HOST}:5432/db", "http://db:5432/db", true},
+		{"${DB_PORT:-5432}", "5432", true},
+		{"${EMPTY:-default}", "default", true},
+		{"${DB_HOST:-localhost}", "db", true},
+		{"${EMPTY}", "", true},
+		{"${DB_PORT:-}", "", true},
+		{"$${DB_HOST} ${DB_HOST}", "${DB_HOST} db", true},
+		{"$DB_HOST {DB_HOST}", "$DB_HOST {DB_HOST}", true},
+		{"http://${DB_USER}@${DB_HOST}", "", false},
+	}
+	for _, tt := range tests {
+		actual, err := interpolateString(tt.value, lookupEnv)
+		if tt.valid != (err == nil) {
+			t.Errorf("Invalid error for %s. Error: %v", tt.value, err)
+		} else if tt.valid && actual != tt.expected {
+			t.Errorf("Invalid interpolation of %s. Actual: %s. Expected: %s", tt.value, actual, tt.expected)
+		}
+	}
+}
+
+func TestLoadInterpolation(t *testing.T) {
+	type interpolationConfig struct {
+		URL      string            `json:"url"`
+		Realms   []string          `json:"realms"`
+		Database map[string]string `json:"database"`
+	}
+	file := writeTestFile(t, "config.json", `{
+  "url": "http://${TEST_DB_HOST}:${TEST_DB_PORT:-5432}/db",
+  "realms": ["${TEST_REALM:-es}", "uk"],
+  "database": {"user": "$${TEST_DB_USER}"}
+}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	os.Setenv("TEST_DB_HOST", "db")
+	defer os.Unsetenv("TEST_DB_HOST")
+	os.Unsetenv("TEST_DB_PORT")
+	os.Unsetenv("TEST_REALM")
+	var cfg interpolationConfig
+	if err := GetConfig(file, &cfg); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+	expected := interpolationConfig{
+		URL:      "http://db:5432/db",
+		Realms:   []string{"es", "uk"},
+		Database: map[string]string{"user": "${TEST_DB_USER}"},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Invalid config. Actual: %+v. Expected: %+v", cfg, expected)
+	}
+}
+
+func TestLoadInterpolationError(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			"object key",
+			"{\n  \"database\": {\n    \"url\": \"http://${TEST_DB_HOST}\"\n  }\n}",
+			`:3:5: key "database.url": environment variable "TEST_DB_HOST" in placeholder ${TEST_DB_HOST} is not set`,
+		},
+		{
+			"array item",
+			"{\n  \"realms\": [\"es\", \"${TEST_REALM}\"]\n}",
+			`:2:3: key "realms.1": environment variable "TEST_REALM" in placeholder ${TEST_REALM} is not set`,
+		},
+	}
+	os.Unsetenv("TEST_DB_HOST")
+	os.Unsetenv("TEST_REALM")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeTestFile(t, "config.json", tt.content)
+			defer os.RemoveAll(filepath.Dir(file))
+			var cfg struct{}
+			err := GetConfig(file, &cfg)
+			expected := "Error processing default configuration. " + file + tt.expected
+			if err == nil || err.Error() != expected {
+				t.Errorf("Invalid error. Actual: %v. Expected: %s", err, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// ConfigSource identifies the source that set a configuration field.
+// Origin is the file path (for ConfigSourceFile and ConfigSourceSecretFile), the URL (for ConfigSourceRemote),
+// the environment variable name (for ConfigSourceEnv), the .env file and variable name (for ConfigSourceDotEnv)
+// or the flag name (for ConfigSourceFlag).
+// It is empty for ConfigSourceDefault (the value was not set by any source).
+type ConfigSource struct {
+	Path   string `json:"path"`
+	Source string `json:"source"`
+	Origin string `json:"origin,omitempty"`
+}
+
+func (s ConfigSource) String() string {
+	if s.Origin == "" {
+		return s.Source
+	}
+	return s.Source + ":" + s.Origin
+}
+
+// ConfigProvenance is the list of sources for every field of the configuration.
+type ConfigProvenance []ConfigSource
+
+// GetProvenance returns the source of every field of the last configuration loaded with Load.
+func (c *ConfigLoader) GetProvenance() ConfigProvenance {
+	provenance := make(ConfigProvenance, 0, len(c.fields))
+	for _, f := range c.fields {
+		source := ConfigSource{Path: f.path, Source: ConfigSourceDefault}
+		if origin, ok := c.fieldOrigin(f.path); ok {
+			source.Source = origin.source
+			source.Origin = origin.name
+		}
+		provenance = append(provenance, source)
+	}
+	return provenance
+}
+
+// fieldOrigin returns the origin of a field. If the field is not set as a whole (e.g. a map set key by key),
+// it returns the origin of the last key.
+func (c *ConfigLoader) fieldOrigin(path string) (configOrigin, bool) {
+	if origin, ok := c.origins[path]; ok {
+		return origin, true
+	}
+	var keys []string
+	for key := range c.origins {
+		if strings.HasPrefix(key, path+".") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return configOrigin{}, false
+	}
+	sort.Strings(keys)
+	return c.origins[keys[len(keys)-1]], true
+}
+
+// Get returns the source of a field path. If the path is not a field (e.g. an element of an array field),
+// it returns the source of its closest parent field. If not found, it returns a ConfigSourceDefault source.
+func (p ConfigProvenance) Get(path string) ConfigSource {
+	for key := path; ; {
+		for _, source := range p {
+			if source.Path == key {
+				return source
+			}
+		}
+		i := strings.LastIndexByte(key, '.')
+		if i < 0 {
+			return ConfigSource{Path: path, Source: ConfigSourceDefault}
+		}
+		key = key[:i]
+	}
+}
+
+// WriteTable writes the provenance as a plain text table with the columns: PATH, SOURCE and ORIGIN.
+func (p ConfigProvenance) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tSOURCE\tORIGIN")
+	for _, source := range p {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", source.Path, source.Source, source.Origin)
+	}
+	return tw.Flush()
+}
+
+func (p ConfigProvenance) String() string {
+	var buf bytes.Buffer
+	p.WriteTable(&buf)
+	return buf.String()
+}
+
+// ConfigProvenanceLogContext is a log context with the source of every configuration field.
+// Each source is in the form "source:origin" (e.g. "env:LOG_LEVEL" or "file:config.json").
+type ConfigProvenanceLogContext struct {
+	Sources map[string]string `json:"configSources,omitempty"`
+}
+
+// LogContext returns a log context with the provenance of the configuration.
+func (p ConfigProvenance) LogContext() *ConfigProvenanceLogContext {
+	sources := make(map[string]string, len(p))
+	for _, source := range p {
+		sources[source.Path] = source.String()
+	}
+	return &ConfigProvenanceLogContext{Sources: sources}
+}
+
+// Log writes the provenance of the configuration as an INFO log record.
+func (p ConfigProvenance) Log(logger *Logger) {
+	logger.InfoC(p.LogContext(), "Configuration sources")
+}
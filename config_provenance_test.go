@@ -0,0 +1,103 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type provenanceConfig struct {
+	Address  string            `json:"address" env:"ADDRESS"`
+	BasePath string            `json:"basePath" env:"BASE_PATH"`
+	LogLevel string            `json:"logLevel" env:"LOG_LEVEL"`
+	Realm    string            `json:"realm" env:"REALM"`
+	Timeout  int               `json:"timeout"`
+	Labels   map[string]string `json:"labels"`
+}
+
+func loadProvenance(t *testing.T) ConfigProvenance {
+	os.Unsetenv("ADDRESS")
+	os.Unsetenv("BASE_PATH")
+	os.Unsetenv("REALM")
+	os.Setenv("LOG_LEVEL", "DEBUG")
+	defer os.Unsetenv("LOG_LEVEL")
+	loader := NewConfigLoader("testdata/config.json")
+	loader.AddLayer("testdata/config.production.json")
+	var cfg provenanceConfig
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+	return loader.GetProvenance()
+}
+
+func TestGetProvenance(t *testing.T) {
+	expected := ConfigProvenance{
+		{Path: "address", Source: ConfigSourceFile, Origin: "testdata/config.production.json"},
+		{Path: "basePath", Source: ConfigSourceFile, Origin: "testdata/config.json"},
+		{Path: "logLevel", Source: ConfigSourceEnv, Origin: "LOG_LEVEL"},
+		{Path: "realm", Source: ConfigSourceFile, Origin: "testdata/config.production.json"},
+		{Path: "timeout", Source: ConfigSourceDefault},
+		{Path: "labels", Source: ConfigSourceDefault},
+	}
+	actual := loadProvenance(t)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Invalid provenance. Actual: %+v. Expected: %+v", actual, expected)
+	}
+	if source := actual.Get("logLevel"); source.String() != "env:LOG_LEVEL" {
+		t.Errorf("Invalid source for logLevel. Actual: %s", source)
+	}
+	if source := actual.Get("unknown"); source.String() != "default" {
+		t.Errorf("Invalid source for unknown field. Actual: %s", source)
+	}
+}
+
+func TestProvenanceTable(t *testing.T) {
+	provenance := ConfigProvenance{
+		{Path: "address", Source: ConfigSourceFile, Origin: "config.json"},
+		{Path: "logLevel", Source: ConfigSourceEnv, Origin: "LOG_LEVEL"},
+		{Path: "timeout", Source: ConfigSourceDefault},
+	}
+	expected := "PATH      SOURCE   ORIGIN\n" +
+		"address   file     config.json\n" +
+		"logLevel  env      LOG_LEVEL\n" +
+		"timeout   default  \n"
+	if actual := provenance.String(); actual != expected {
+		t.Errorf("Invalid provenance table. Actual:\n%s\nExpected:\n%s", actual, expected)
+	}
+}
+
+func TestProvenanceLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{out: &buf, logLevel: infoLevel}
+	provenance := ConfigProvenance{
+		{Path: "address", Source: ConfigSourceFile, Origin: "config.json"},
+		{Path: "logLevel", Source: ConfigSourceEnv, Origin: "LOG_LEVEL"},
+	}
+	provenance.Log(logger)
+	expected := `,"lvl":"INFO","configSources":{"address":"file:config.json","logLevel":"env:LOG_LEVEL"},"msg":"Configuration sources"}` + "\n"
+	if actual := extractFirstField(buf.String()); actual != expected {
+		t.Errorf("Invalid provenance log. Actual: %s. Expected: %s", actual, expected)
+	}
+	if !strings.HasPrefix(buf.String(), `{"time":`) {
+		t.Errorf("Invalid provenance log. Actual: %s", buf.String())
+	}
+}
@@ -0,0 +1,192 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultRemoteConfigTimeout is the default timeout to fetch a remote configuration.
+var DefaultRemoteConfigTimeout = 10 * time.Second
+
+// RemoteConfig is a configuration layer with a JSON document fetched from an HTTP endpoint (e.g. a config server).
+// The document is requested with the ETag of the last response (If-None-Match) to avoid downloading it again
+// if it has not changed. If a cache file is set, the last document is also stored on disk, so the configuration
+// can be loaded from this copy when the endpoint is unreachable (e.g. when the service restarts during an outage
+// of the config server).
+type RemoteConfig struct {
+	url       string
+	timeout   time.Duration
+	cacheFile string
+	client    *http.Client
+	mutex     sync.Mutex
+	etag      string
+	data      []byte
+}
+
+// NewRemoteConfig creates a remote configuration layer for a URL.
+func NewRemoteConfig(url string) *RemoteConfig {
+	return &RemoteConfig{url: url, timeout: DefaultRemoteConfigTimeout}
+}
+
+// SetTimeout sets the timeout of the request to fetch the configuration.
+func (r *RemoteConfig) SetTimeout(timeout time.Duration) {
+	r.timeout = timeout
+}
+
+// SetCacheFile sets the file to store the last configuration fetched. The ETag is stored in the same path
+// with the .etag extension.
+func (r *RemoteConfig) SetCacheFile(cacheFile string) {
+	r.cacheFile = cacheFile
+}
+
+// SetClient sets the HTTP client to fetch the configuration (e.g. to configure TLS). Its timeout is overridden
+// by the timeout of the remote configuration.
+func (r *RemoteConfig) SetClient(client *http.Client) {
+	r.client = client
+}
+
+// URL returns the URL of the remote configuration.
+func (r *RemoteConfig) URL() string {
+	return r.url
+}
+
+// read fetches the configuration document. If the request fails, it returns the cached document (from memory or
+// from the cache file) with the fetch error as warning. The error is only returned if there is no cached document.
+func (r *RemoteConfig) read() (doc *configDocument, warning error, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.data == nil {
+		r.readCache()
+	}
+	if modified, err := r.fetch(); err != nil {
+		if r.data == nil {
+			return nil, nil, err
+		}
+		warning = fmt.Errorf("%s (using the cached configuration)", err)
+	} else if modified {
+		warning = r.writeCache()
+	}
+	doc, err = decodeJSONConfig(r.url, r.data)
+	return doc, warning, err
+}
+
+// fetch requests the configuration document. It keeps the current document if it has not been modified.
+func (r *RemoteConfig) fetch() (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return false, fmt.Errorf("cannot fetch remote configuration %s: %s", r.url, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if r.etag != "" && r.data != nil {
+		req.Header.Set("If-None-Match", r.etag)
+	}
+	client := http.Client{Timeout: r.timeout}
+	if r.client != nil {
+		client = *r.client
+		client.Timeout = r.timeout
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("cannot fetch remote configuration: %s", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && r.data != nil:
+		return false, nil
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("cannot fetch remote configuration %s: unexpected status %d", r.url, resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("cannot fetch remote configuration %s: %s", r.url, err)
+	}
+	if _, err := decodeJSONConfig(r.url, data); err != nil {
+		return false, err
+	}
+	r.data = data
+	r.etag = resp.Header.Get("ETag")
+	return true, nil
+}
+
+// readCache reads the document and the ETag from the cache file (if any).
+func (r *RemoteConfig) readCache() {
+	if r.cacheFile == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(r.cacheFile)
+	if err != nil {
+		return
+	}
+	etag, _ := ioutil.ReadFile(r.cacheFile + ".etag")
+	r.data = data
+	r.etag = string(etag)
+}
+
+// writeCache stores the document and the ETag in the cache file (if any). The files are replaced atomically.
+func (r *RemoteConfig) writeCache() error {
+	if r.cacheFile == "" {
+		return nil
+	}
+	if err := writeFileAtomic(r.cacheFile, r.data); err != nil {
+		return fmt.Errorf("cannot write remote configuration cache: %s", err)
+	}
+	if err := writeFileAtomic(r.cacheFile+".etag", []byte(r.etag)); err != nil {
+		return fmt.Errorf("cannot write remote configuration cache: %s", err)
+	}
+	return nil
+}
+
+func writeFileAtomic(file string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// AddRemote appends a remote configuration layer that overrides the previous layers. The environment variables
+// and the command-line flags still override the remote configuration.
+func (c *ConfigLoader) AddRemote(remote *RemoteConfig) {
+	c.layers = append(c.layers, configLayer{file: remote.URL(), remote: remote})
+}
+
+// isRemoteLayer checks if a layer name is the URL of a remote configuration.
+func (c *ConfigLoader) isRemoteLayer(name string) bool {
+	for _, layer := range c.layers {
+		if layer.remote != nil && layer.file == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadRemoteConfig(t *testing.T) {
+	body := `{"basePath": "/remote", "logLevel": "DEBUG"}`
+	var requests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "govice")
+	if err != nil {
+		t.Fatalf("Error creating temporary directory. %s", err)
+	}
+	defer os.RemoveAll(dir)
+	cacheFile := filepath.Join(dir, "remote.json")
+	os.Unsetenv("ADDRESS")
+	os.Setenv("LOG_LEVEL", "ERROR")
+	defer os.Unsetenv("LOG_LEVEL")
+
+	remote := NewRemoteConfig(server.URL)
+	remote.SetTimeout(time.Second)
+	remote.SetCacheFile(cacheFile)
+	loader := NewConfigLoader("testdata/config.json")
+	loader.AddRemote(remote)
+	for i := 0; i < 2; i++ {
+		var cfg config
+		if err := loader.Load(&cfg); err != nil {
+			t.Fatalf("Error loading config. %s", err)
+		}
+		expected := config{Address: ":80", BasePath: "/remote", LogLevel: "ERROR", Realm: "es"}
+		if cfg != expected {
+			t.Errorf("Invalid config. Actual: %+v. Expected: %+v", cfg, expected)
+		}
+	}
+	if requests != 2 || notModified != 1 {
+		t.Errorf("Invalid requests. Actual: %d (%d not modified)", requests, notModified)
+	}
+	if source := loader.GetProvenance().Get("basePath"); source.String() != "remote:"+server.URL {
+		t.Errorf("Invalid source for basePath. Actual: %s", source)
+	}
+	if data, err := ioutil.ReadFile(cacheFile); err != nil || string(data) != body {
+		t.Errorf("Invalid cache file. Actual: %s (%v)", data, err)
+	}
+
+	// A new loader uses the cached copy on disk when the endpoint is unreachable
+	server.Close()
+	var buf bytes.Buffer
+	logger := NewLogger()
+	logger.SetWriter(&buf)
+	remote = NewRemoteConfig(server.URL)
+	remote.SetCacheFile(cacheFile)
+	loader = NewConfigLoader("testdata/config.json")
+	loader.SetLogger(logger)
+	loader.AddRemote(remote)
+	var cfg config
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Error loading config from cache. %s", err)
+	}
+	if cfg.BasePath != "/remote" {
+		t.Errorf("Invalid config from cache. Actual: %+v", cfg)
+	}
+	if !strings.Contains(buf.String(), "Error reading remote configuration. cannot fetch remote configuration") {
+		t.Errorf("Invalid warning. Actual: %s", buf.String())
+	}
+}
+
+func TestLoadRemoteConfigErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+	}{
+		{"unexpected status", http.StatusInternalServerError, "", "unexpected status 500"},
+		{"invalid document", http.StatusOK, `{"basePath": }`, "invalid character"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			loader := NewConfigLoader("testdata/config.json")
+			loader.AddRemote(NewRemoteConfig(server.URL))
+			err := loader.Load(&config{})
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Invalid error. Actual: %v. Expected: %s", err, tt.expected)
+			}
+		})
+	}
+
+	// Timeout without cached copy
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	remote := NewRemoteConfig(server.URL)
+	remote.SetTimeout(50 * time.Millisecond)
+	loader := NewConfigLoader("testdata/config.json")
+	loader.AddRemote(remote)
+	if err := loader.Load(&config{}); err == nil || !strings.Contains(err.Error(), "cannot fetch remote configuration") {
+		t.Errorf("Invalid error. Actual: %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONSchemaDraft is the JSON schema version of the schemas generated by GenerateSchema.
+const JSONSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	ipType            = reflect.TypeOf(net.IP{})
+)
+
+// GenerateSchema generates a JSON schema (draft-07) for a configuration struct (or a pointer to it) that can be
+// loaded by Validator.LoadSchemas. It uses the json struct tags to name the properties, and the following
+// optional struct tags to set constraints:
+//   - required:"true". The property is required.
+//   - min:"1" and max:"10". Minimum and maximum value for numbers, length for strings and items for arrays.
+//   - enum:"DEBUG,INFO". Comma-separated list of valid values.
+//   - pattern:"^/". Regular expression for strings.
+//
+// Nested structs do not admit additional properties. Pointers, slices and maps also admit null values unless
+// they are omitted when empty (omitempty option). The types decoded as text by the loader are strings:
+// time.Duration and ByteSize (which also admit a number), url.URL (uri format), regexp.Regexp and net.IP
+// (ipv4 or ipv6 format).
+func GenerateSchema(config interface{}) ([]byte, error) {
+	t := reflect.TypeOf(config)
+	if t == nil || derefType(t).Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Error generating JSON schema. Expected a struct")
+	}
+	schema, err := typeSchema(derefType(t), map[reflect.Type]bool{})
+	if err != nil {
+		return nil, fmt.Errorf("Error generating JSON schema. %s", err)
+	}
+	schema["$schema"] = JSONSchemaDraft
+	return json.MarshalIndent(schema, "", "    ")
+}
+
+// typeSchema returns the JSON schema of a type according to its JSON representation.
+func typeSchema(t reflect.Type, visited map[reflect.Type]bool) (map[string]interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		return typeSchema(t.Elem(), visited)
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	case t == durationType, t == byteSizeType:
+		return map[string]interface{}{"type": []string{"string", "integer"}}, nil
+	case t == urlType:
+		return map[string]interface{}{"type": "string", "format": "uri"}, nil
+	case t == regexpType:
+		return map[string]interface{}{"type": "string", "format": "regex"}, nil
+	case t == ipType:
+		return map[string]interface{}{
+			"type":  "string",
+			"anyOf": []interface{}{map[string]interface{}{"format": "ipv4"}, map[string]interface{}{"format": "ipv6"}},
+		}, nil
+	case t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType):
+		return map[string]interface{}{}, nil
+	case t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType):
+		return map[string]interface{}{"type": "string"}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is marshalled as a base64 string
+			return map[string]interface{}{"type": "string"}, nil
+		}
+		items, err := typeSchema(t.Elem(), visited)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		values, err := typeSchema(t.Elem(), visited)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return structSchema(t, visited)
+	}
+	return nil, fmt.Errorf("type %s is not supported", t)
+}
+
+func structSchema(t reflect.Type, visited map[reflect.Type]bool) (map[string]interface{}, error) {
+	if visited[t] {
+		// Recursive types are not expanded
+		return map[string]interface{}{"type": "object"}, nil
+	}
+	visited[t] = true
+	defer delete(visited, t)
+	properties := map[string]interface{}{}
+	var required []string
+	if err := appendStructProperties(t, visited, properties, &required); err != nil {
+		return nil, err
+	}
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties":           properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema, nil
+}
+
+func appendStructProperties(t reflect.Type, visited map[reflect.Type]bool, properties map[string]interface{}, required *[]string) error {
+	// Fields of embedded structs are promoted unless a shallower field has the same name
+	fields := jsonFields(t)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return lessIndex(fields[names[i]].Index, fields[names[j]].Index)
+	})
+	for _, name := range names {
+		field := fields[name]
+		schema, err := typeSchema(field.Type, visited)
+		if err != nil {
+			return fmt.Errorf("field %s: %s", field.Name, err)
+		}
+		if err := setSchemaConstraints(schema, field); err != nil {
+			return fmt.Errorf("field %s: %s", field.Name, err)
+		}
+		if isNullableType(field.Type) && !strings.Contains(field.Tag.Get("json"), ",omitempty") {
+			// Nil pointers, slices and maps are marshalled as null
+			switch typ := schema["type"].(type) {
+			case string:
+				schema["type"] = []string{typ, "null"}
+			case []string:
+				schema["type"] = append(typ, "null")
+			}
+		}
+		if field.Tag.Get("required") == "true" {
+			*required = append(*required, name)
+		}
+		properties[name] = schema
+	}
+	return nil
+}
+
+// lessIndex sorts the fields in declaration order.
+func lessIndex(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func isNullableType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// setSchemaConstraints adds the constraints from the struct tags (min, max, enum and pattern).
+func setSchemaConstraints(schema map[string]interface{}, field reflect.StructField) error {
+	minKey, maxKey := "minimum", "maximum"
+	switch schema["type"] {
+	case "string":
+		minKey, maxKey = "minLength", "maxLength"
+	case "array":
+		minKey, maxKey = "minItems", "maxItems"
+	}
+	for key, tag := range map[string]string{minKey: "min", maxKey: "max"} {
+		value, ok := field.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s tag: %s", tag, value)
+		}
+		schema[key] = n
+	}
+	if enum, ok := field.Tag.Lookup("enum"); ok {
+		var values []interface{}
+		for _, value := range strings.Split(enum, ",") {
+			value = strings.TrimSpace(value)
+			switch schema["type"] {
+			case "integer", "number":
+				n, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return fmt.Errorf("invalid enum tag: %s", enum)
+				}
+				values = append(values, n)
+			case "boolean":
+				b, err := strconv.ParseBool(value)
+				if err != nil {
+					return fmt.Errorf("invalid enum tag: %s", enum)
+				}
+				values = append(values, b)
+			default:
+				values = append(values, value)
+			}
+		}
+		delete(schema, "type")
+		schema["enum"] = values
+	}
+	if pattern, ok := field.Tag.Lookup("pattern"); ok {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern tag: %s", err)
+		}
+		schema["pattern"] = pattern
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"encoding/json"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type schemaBaseConfig struct {
+	Address string `json:"address" required:"true" pattern:"^[^:]*:[0-9]+$"`
+}
+
+type schemaConfig struct {
+	schemaBaseConfig
+	LogLevel string            `json:"logLevel" enum:"DEBUG,INFO,WARN,ERROR,FATAL"`
+	Workers  uint              `json:"workers" min:"1" max:"16"`
+	Timeout  time.Time         `json:"timeout"`
+	Realms   []string          `json:"realms" min:"1"`
+	Headers  map[string]string `json:"headers"`
+	Ports    []int             `json:"ports,omitempty"`
+	Ignored  string            `json:"-"`
+	internal string
+}
+
+func TestGenerateSchema(t *testing.T) {
+	schema, err := GenerateSchema(&schemaConfig{})
+	if err != nil {
+		t.Fatalf("Error generating schema. %s", err)
+	}
+	var actual map[string]interface{}
+	if err := json.Unmarshal(schema, &actual); err != nil {
+		t.Fatalf("Invalid schema. %s", err)
+	}
+	var expected map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["address"],
+		"properties": {
+			"address": {"type": "string", "pattern": "^[^:]*:[0-9]+$"},
+			"logLevel": {"enum": ["DEBUG", "INFO", "WARN", "ERROR", "FATAL"]},
+			"workers": {"type": "integer", "minimum": 1, "maximum": 16},
+			"timeout": {"type": "string", "format": "date-time"},
+			"realms": {"type": ["array", "null"], "items": {"type": "string"}, "minItems": 1},
+			"headers": {"type": ["object", "null"], "additionalProperties": {"type": "string"}},
+			"ports": {"type": "array", "items": {"type": "integer"}}
+		}
+	}`), &expected)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Invalid schema. Actual: %s", schema)
+	}
+}
+
+func TestGenerateSchemaTextTypes(t *testing.T) {
+	schema, err := GenerateSchema(struct {
+		Timeout  time.Duration  `json:"timeout"`
+		MaxBody  ByteSize       `json:"maxBody"`
+		Endpoint *url.URL       `json:"endpoint,omitempty"`
+		Path     *regexp.Regexp `json:"path,omitempty"`
+		Listen   net.IP         `json:"listen,omitempty"`
+	}{})
+	if err != nil {
+		t.Fatalf("Error generating schema. %s", err)
+	}
+	var actual map[string]interface{}
+	if err := json.Unmarshal(schema, &actual); err != nil {
+		t.Fatalf("Invalid schema. %s", err)
+	}
+	var expected map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"timeout": {"type": ["string", "integer"]},
+		"maxBody": {"type": ["string", "integer"]},
+		"endpoint": {"type": "string", "format": "uri"},
+		"path": {"type": "string", "format": "regex"},
+		"listen": {"type": "string", "anyOf": [{"format": "ipv4"}, {"format": "ipv6"}]}
+	}`), &expected)
+	if !reflect.DeepEqual(actual["properties"], expected) {
+		t.Errorf("Invalid schema. Actual: %s", schema)
+	}
+}
+
+func TestGenerateSchemaShadowedFields(t *testing.T) {
+	schema, err := GenerateSchema(&struct {
+		Address int `json:"address"`
+		schemaBaseConfig
+	}{})
+	if err != nil {
+		t.Fatalf("Error generating schema. %s", err)
+	}
+	var actual map[string]interface{}
+	if err := json.Unmarshal(schema, &actual); err != nil {
+		t.Fatalf("Invalid schema. %s", err)
+	}
+	var expected map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"address": {"type": "integer"}
+	}`), &expected)
+	if !reflect.DeepEqual(actual["properties"], expected) || actual["required"] != nil {
+		t.Errorf("Invalid schema. Actual: %s", schema)
+	}
+}
+
+func TestGenerateSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config interface{}
+	}{
+		{"not a struct", "config"},
+		{"nil", nil},
+		{"invalid min", &struct {
+			Port int `json:"port" min:"a"`
+		}{}},
+		{"invalid enum", &struct {
+			Port int `json:"port" enum:"80,a"`
+		}{}},
+		{"invalid pattern", &struct {
+			Path string `json:"path" pattern:"("`
+		}{}},
+		{"unsupported type", &struct {
+			Handler func() `json:"handler"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := GenerateSchema(tt.config); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
+
+func TestGenerateSchemaValidator(t *testing.T) {
+	schema, err := GenerateSchema(schemaConfig{})
+	if err != nil {
+		t.Fatalf("Error generating schema. %s", err)
+	}
+	file := writeTestFile(t, "config.json", string(schema))
+	defer os.RemoveAll(filepath.Dir(file))
+	validator := NewValidator()
+	if err := validator.LoadSchemas(filepath.Dir(file)); err != nil {
+		t.Fatalf("Error loading generated schema. %s", err)
+	}
+	tests := []struct {
+		name   string
+		config schemaConfig
+		valid  bool
+	}{
+		{"valid", schemaConfig{schemaBaseConfig: schemaBaseConfig{Address: ":80"}, LogLevel: "INFO", Workers: 2, Realms: []string{"es"}}, true},
+		{"invalid address", schemaConfig{schemaBaseConfig: schemaBaseConfig{Address: "localhost"}, LogLevel: "INFO", Workers: 2, Realms: []string{"es"}}, false},
+		{"invalid log level", schemaConfig{schemaBaseConfig: schemaBaseConfig{Address: ":80"}, LogLevel: "TRACE", Workers: 2, Realms: []string{"es"}}, false},
+		{"invalid workers", schemaConfig{schemaBaseConfig: schemaBaseConfig{Address: ":80"}, LogLevel: "INFO", Workers: 20, Realms: []string{"es"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidateConfig("config", &tt.config)
+			if tt.valid != (err == nil) {
+				t.Errorf("Invalid validation. Expected valid: %t. Error: %v", tt.valid, err)
+			}
+		})
+	}
+}
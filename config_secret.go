@@ -0,0 +1,186 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// SecretsDir is the directory where docker and kubernetes mount the secrets.
+// A secret field bound to the environment variable DB_PASSWORD is read from the file db_password
+// in this directory (if the environment variables DB_PASSWORD and DB_PASSWORD_FILE are not set).
+var SecretsDir = "/run/secrets"
+
+// RedactedValue replaces the value of the secret fields when the configuration is marshalled with
+// MarshalRedacted or logged.
+var RedactedValue = "******"
+
+// isSecretField checks if the field is tagged as secret (e.g. `secret:"true"`).
+func isSecretField(field reflect.StructField) bool {
+	return strings.ToLower(field.Tag.Get("secret")) == "true"
+}
+
+// readSecretFile reads the value of a secret bound to an environment variable from a file. The file is
+// set by the environment variable with the "_FILE" suffix, or it is stored in SecretsDir.
+// It returns the value, the path of the file and whether the secret file was found.
+func readSecretFile(envName string, lookupEnv func(string) (string, bool)) (string, string, bool, error) {
+	path, ok := lookupEnv(envName + "_FILE")
+	if !ok {
+		path = filepath.Join(SecretsDir, strings.ToLower(envName))
+		if _, err := os.Stat(path); err != nil {
+			return "", "", false, nil
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", path, false, err
+	}
+	return strings.TrimRight(string(data), "\r\n"), path, true, nil
+}
+
+// secretTypes caches if a type contains secret fields.
+var secretTypes sync.Map
+
+// hasSecrets checks if a type contains secret fields (directly or in nested structs, slices or maps). Interfaces
+// may contain secrets, so they are checked with the dynamic value.
+func hasSecrets(t reflect.Type) bool {
+	if cached, ok := secretTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	found := findSecrets(t, map[reflect.Type]bool{})
+	secretTypes.Store(t, found)
+	return found
+}
+
+func findSecrets(t reflect.Type, visited map[reflect.Type]bool) bool {
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return findSecrets(t.Elem(), visited)
+	case reflect.Struct:
+		if visited[t] {
+			return false
+		}
+		visited[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			if isSecretField(field) || findSecrets(field.Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Redact returns a copy of v where the value of the secret fields is replaced with RedactedValue
+// (string fields) or with the zero value (fields of other types). Empty secrets are kept empty.
+// If v does not contain secret fields, it is returned as is.
+func Redact(v interface{}) interface{} {
+	if v == nil || !hasSecrets(reflect.TypeOf(v)) {
+		return v
+	}
+	return redactValue(reflect.ValueOf(v)).Interface()
+}
+
+func redactValue(v reflect.Value) reflect.Value {
+	if !hasSecrets(v.Type()) {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(redactValue(v.Elem()))
+		return c
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(redactValue(v.Elem()))
+		return p
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		if v.Kind() == reflect.Slice {
+			c.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		}
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			c.SetMapIndex(key, redactValue(v.MapIndex(key)))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			f := c.Field(i)
+			if !f.CanSet() {
+				// The exported fields of an unexported embedded struct are marshalled as fields of the parent,
+				// so the embedded struct (already copied into c) is redacted in place.
+				f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+			}
+			if isSecretField(field) {
+				if !f.IsZero() {
+					if field.Type.Kind() == reflect.String {
+						f.SetString(RedactedValue)
+					} else {
+						f.Set(reflect.Zero(field.Type))
+					}
+				}
+				continue
+			}
+			f.Set(redactValue(f))
+		}
+		return c
+	}
+	return v
+}
+
+// MarshalRedacted marshals v to JSON replacing the value of the secret fields (see Redact).
+// It is useful to log the configuration.
+func MarshalRedacted(v interface{}) ([]byte, error) {
+	return json.Marshal(Redact(v))
+}
@@ -0,0 +1,156 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type secretDBConfig struct {
+	User     string `json:"user" env:"TEST_DB_USER"`
+	Password string `json:"password" env:"TEST_DB_PASSWORD" secret:"true"`
+}
+
+type secretConfig struct {
+	Address  string            `json:"address"`
+	Token    string            `json:"token,omitempty" env:"TEST_TOKEN" secret:"true"`
+	Database secretDBConfig    `json:"database"`
+	Replicas []*secretDBConfig `json:"replicas,omitempty"`
+}
+
+func TestGetConfigSecretFile(t *testing.T) {
+	file := writeTestFile(t, "db_password", "filePassword\n")
+	dir := filepath.Dir(file)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("fileToken"), 0600); err != nil {
+		t.Fatalf("Error writing token file. %s", err)
+	}
+	oldSecretsDir := SecretsDir
+	defer func() { SecretsDir = oldSecretsDir }()
+	os.Unsetenv("TEST_DB_PASSWORD")
+	os.Unsetenv("TEST_DB_PASSWORD_FILE")
+	os.Unsetenv("TEST_TOKEN")
+	os.Setenv("TEST_TOKEN_FILE", tokenFile)
+	defer os.Unsetenv("TEST_TOKEN_FILE")
+
+	// Secret stored in the secrets directory (named as the environment variable in lower case)
+	SecretsDir = dir
+	os.Rename(file, filepath.Join(dir, "test_db_password"))
+	loader := NewConfigLoader("testdata/config.json")
+	var cfg secretConfig
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+	if cfg.Database.Password != "filePassword" || cfg.Token != "fileToken" {
+		t.Errorf("Invalid secrets. Actual: %+v", cfg)
+	}
+	provenance := loader.GetProvenance()
+	if source := provenance.Get("token"); source.Source != ConfigSourceSecretFile || source.Origin != tokenFile {
+		t.Errorf("Invalid source for token. Actual: %+v", source)
+	}
+
+	// Environment variable has precedence over the secret files
+	os.Setenv("TEST_DB_PASSWORD", "envPassword")
+	defer os.Unsetenv("TEST_DB_PASSWORD")
+	cfg = secretConfig{}
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+	if cfg.Database.Password != "envPassword" {
+		t.Errorf("Invalid password. Actual: %s", cfg.Database.Password)
+	}
+
+	// Unreadable secret file
+	os.Setenv("TEST_TOKEN_FILE", filepath.Join(dir, "notExistent"))
+	if err := loader.Load(&cfg); err == nil {
+		t.Errorf("Expected error with an unreadable secret file")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	cfg := secretConfig{
+		Address:  ":80",
+		Token:    "token",
+		Database: secretDBConfig{User: "user", Password: "password"},
+		Replicas: []*secretDBConfig{{User: "replica", Password: "replicaPassword"}, {User: "empty"}},
+	}
+	data, err := MarshalRedacted(&cfg)
+	if err != nil {
+		t.Fatalf("Error marshalling config. %s", err)
+	}
+	expected := `{"address":":80","token":"******","database":{"user":"user","password":"******"},` +
+		`"replicas":[{"user":"replica","password":"******"},{"user":"empty","password":""}]}`
+	if string(data) != expected {
+		t.Errorf("Invalid redacted config. Actual: %s. Expected: %s", data, expected)
+	}
+	if cfg.Token != "token" || cfg.Database.Password != "password" || cfg.Replicas[0].Password != "replicaPassword" {
+		t.Errorf("Redact must not modify the original config. Actual: %+v", cfg)
+	}
+	ctxt := LogContext{User: "user"}
+	if Redact(ctxt) != ctxt {
+		t.Errorf("Redact must return the same object without secrets")
+	}
+}
+
+type secretCredentials struct {
+	Password string `json:"password" secret:"true"`
+}
+
+type secretEmbeddedConfig struct {
+	Address string `json:"address"`
+	secretCredentials
+	Extra map[string]interface{} `json:"extra,omitempty"`
+	Any   interface{}            `json:"any,omitempty"`
+}
+
+func TestRedactEmbeddedAndInterfaces(t *testing.T) {
+	cfg := secretEmbeddedConfig{
+		Address:           ":80",
+		secretCredentials: secretCredentials{Password: "password"},
+		Extra:             map[string]interface{}{"db": secretDBConfig{User: "user", Password: "dbPassword"}},
+		Any:               &secretCredentials{Password: "anyPassword"},
+	}
+	data, err := MarshalRedacted(cfg)
+	if err != nil {
+		t.Fatalf("Error marshalling config. %s", err)
+	}
+	expected := `{"address":":80","password":"******","extra":{"db":{"user":"user","password":"******"}},` +
+		`"any":{"password":"******"}}`
+	if string(data) != expected {
+		t.Errorf("Invalid redacted config. Actual: %s. Expected: %s", data, expected)
+	}
+	if cfg.Password != "password" || cfg.Extra["db"].(secretDBConfig).Password != "dbPassword" ||
+		cfg.Any.(*secretCredentials).Password != "anyPassword" {
+		t.Errorf("Redact must not modify the original config. Actual: %+v", cfg)
+	}
+}
+
+func TestLogRedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{out: &buf, logLevel: infoLevel}
+	logger.InfoC(secretDBConfig{User: "user", Password: "password"}, "Configuration")
+	expected := `,"lvl":"INFO","user":"user","password":"******","msg":"Configuration"}` + "\n"
+	if actual := extractFirstField(buf.String()); actual != expected {
+		t.Errorf("Invalid log. Actual: %s. Expected: %s", actual, expected)
+	}
+}
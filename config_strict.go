@@ -0,0 +1,178 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// StrictMode sets how the ConfigLoader handles the keys of the configuration files that do not match
+// any field of the configuration struct (e.g. a typo like "logLevl").
+type StrictMode int
+
+const (
+	// StrictModeOff ignores the unknown keys (as json.Unmarshal does).
+	StrictModeOff StrictMode = iota
+	// StrictModeWarn logs a warning for every unknown key.
+	StrictModeWarn
+	// StrictModeError rejects the configuration if there is any unknown key.
+	StrictModeError
+)
+
+// errUnknownKey is the error of a ConfigFileError for an unknown key.
+var errUnknownKey = errors.New("unknown key")
+
+// SetStrictMode sets how the unknown keys of the configuration files are handled. By default, they are ignored.
+func (c *ConfigLoader) SetStrictMode(mode StrictMode) {
+	c.strictMode = mode
+}
+
+// SetLogger sets the logger for the warnings of the loader (e.g. unknown keys with StrictModeWarn).
+func (c *ConfigLoader) SetLogger(logger *Logger) {
+	c.logger = logger
+}
+
+// getLogger returns the logger for the warnings of the loader (a new logger if none is set).
+func (c *ConfigLoader) getLogger() *Logger {
+	if c.logger == nil {
+		return NewLogger()
+	}
+	return c.logger
+}
+
+// checkUnknownKeys reports the unknown keys of the document according to the strict mode.
+func (c *ConfigLoader) checkUnknownKeys(doc *configDocument, t reflect.Type) error {
+	if c.strictMode == StrictModeOff {
+		return nil
+	}
+	var paths []string
+	findUnknownKeys(doc.values, t, "", &paths)
+	var errs []string
+	for _, path := range paths {
+		pos := doc.keyPosition(path)
+		err := &ConfigFileError{File: doc.keyFile(path), Key: path, Line: pos.Line, Column: pos.Column, Err: errUnknownKey}
+		if c.strictMode == StrictModeWarn {
+			c.getLogger().Warn("Ignoring configuration key. %s", err)
+			continue
+		}
+		errs = append(errs, err.Error())
+	}
+	return joinConfigErrors(errs)
+}
+
+// findUnknownKeys appends the paths of the object keys in the value that are not decoded into the type
+// (following the matching rules of json.Unmarshal).
+func findUnknownKeys(value interface{}, t reflect.Type, path string, paths *[]string) {
+	t = derefType(t)
+	ptr := reflect.PtrTo(t)
+	if ptr.Implements(jsonUnmarshalerType) || ptr.Implements(textUnmarshalerType) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for _, key := range sortedKeys(object) {
+			keyPath := joinConfigPath(path, key)
+			field, ok := lookupJSONField(fields, key)
+			if !ok {
+				*paths = append(*paths, keyPath)
+				continue
+			}
+			findUnknownKeys(object[key], field.Type, keyPath, paths)
+		}
+	case reflect.Map:
+		if object, ok := value.(map[string]interface{}); ok {
+			for _, key := range sortedKeys(object) {
+				findUnknownKeys(object[key], t.Elem(), joinConfigPath(path, key), paths)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if items, ok := value.([]interface{}); ok {
+			for i, item := range items {
+				findUnknownKeys(item, t.Elem(), joinConfigPath(path, strconv.Itoa(i)), paths)
+			}
+		}
+	}
+}
+
+// jsonFields returns the fields of a struct indexed by their json name. The index of the promoted fields of
+// embedded structs is relative to the struct. They are promoted unless a shallower field has the same name.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	return appendJSONFields(t, nil, map[string]reflect.StructField{}, map[reflect.Type]bool{})
+}
+
+func appendJSONFields(t reflect.Type, index []int, fields map[string]reflect.StructField, visited map[reflect.Type]bool) map[string]reflect.StructField {
+	visited[t] = true
+	type embeddedStruct struct {
+		t     reflect.Type
+		index []int
+	}
+	var embedded []embeddedStruct
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		field.Index = append(append([]int{}, index...), i)
+		tag := field.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && derefType(field.Type).Kind() == reflect.Struct {
+			embedded = append(embedded, embeddedStruct{derefType(field.Type), field.Index})
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	for _, e := range embedded {
+		if visited[e.t] {
+			continue
+		}
+		for name, field := range appendJSONFields(e.t, e.index, map[string]reflect.StructField{}, visited) {
+			if _, ok := fields[name]; !ok {
+				fields[name] = field
+			}
+		}
+	}
+	return fields
+}
+
+// lookupJSONField finds a field by its json name. As json.Unmarshal, it prefers an exact match but it also
+// accepts a case-insensitive match.
+func lookupJSONField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	if field, ok := fields[key]; ok {
+		return field, true
+	}
+	for name, field := range fields {
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
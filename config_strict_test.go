@@ -0,0 +1,106 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type strictBaseConfig struct {
+	Address string `json:"address"`
+}
+
+type strictConfig struct {
+	strictBaseConfig
+	LogLevel string                       `json:"logLevel"`
+	Ignored  string                       `json:"-"`
+	Started  time.Time                    `json:"started"`
+	Backends []strictBaseConfig           `json:"backends"`
+	Realms   map[string]*strictBaseConfig `json:"realms"`
+	Extra    map[string]interface{}       `json:"extra"`
+}
+
+func TestFindUnknownKeys(t *testing.T) {
+	values := map[string]interface{}{
+		"address":  ":80",
+		"LOGLEVEL": "INFO",
+		"logLevl":  "DEBUG",
+		"Ignored":  "x",
+		"started":  "2018-01-02T03:04:05Z",
+		"backends": []interface{}{
+			map[string]interface{}{"address": ":81"},
+			map[string]interface{}{"adress": ":82"},
+		},
+		"realms": map[string]interface{}{
+			"es": map[string]interface{}{"address": ":83", "port": 83},
+		},
+		"extra": map[string]interface{}{"any": map[string]interface{}{"key": 1}},
+	}
+	var paths []string
+	findUnknownKeys(values, reflect.TypeOf(strictConfig{}), "", &paths)
+	expected := []string{"Ignored", "backends.1.adress", "logLevl", "realms.es.port"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Invalid unknown keys. Actual: %v. Expected: %v", paths, expected)
+	}
+}
+
+func TestLoadStrictMode(t *testing.T) {
+	file := writeTestFile(t, "config.json", "{\n  \"address\": \":80\",\n  \"logLevl\": \"DEBUG\",\n  \"backends\": [{\"adress\": \":81\"}]\n}")
+	defer os.RemoveAll(filepath.Dir(file))
+
+	// Default mode ignores the unknown keys
+	var cfg strictConfig
+	if err := NewConfigLoader(file).Load(&cfg); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+
+	// Error mode rejects the configuration
+	loader := NewConfigLoader(file)
+	loader.SetStrictMode(StrictModeError)
+	err := loader.Load(&cfg)
+	expected := "Error processing default configuration. " +
+		file + `:4:3: key "backends.0.adress": unknown key. ` +
+		file + `:3:3: key "logLevl": unknown key`
+	if err == nil || err.Error() != expected {
+		t.Errorf("Invalid error. Actual: %v. Expected: %s", err, expected)
+	}
+
+	// Warn mode logs the unknown keys
+	var buf bytes.Buffer
+	logger := NewLogger()
+	logger.SetWriter(&buf)
+	loader.SetStrictMode(StrictModeWarn)
+	loader.SetLogger(logger)
+	cfg = strictConfig{}
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Error loading config in warn mode. %s", err)
+	}
+	if cfg.Address != ":80" {
+		t.Errorf("Invalid config. Actual: %+v", cfg)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"lvl":"WARN"`) || !strings.Contains(lines[1], `key \"logLevl\": unknown key`) {
+		t.Errorf("Invalid warnings. Actual: %s", buf.String())
+	}
+}
@@ -17,8 +17,11 @@
 
 package govice
 
-import "testing"
-import "os"
+import (
+	"os"
+	"reflect"
+	"testing"
+)
 
 type config struct {
 	Address  string `json:"address" env:"ADDRESS"`
@@ -58,3 +61,60 @@ func TestGetConfigWrongFile(t *testing.T) {
 		t.Errorf("Invalid error getting configuration. %s", err)
 	}
 }
+
+func TestGetConfigLayers(t *testing.T) {
+	os.Unsetenv("ADDRESS")
+	os.Unsetenv("LOG_LEVEL")
+	expected := config{Address: ":8443", BasePath: "/users", LogLevel: "INFO", Realm: "uk"}
+	var actual config
+	files := []string{"testdata/config.json", "testdata/config.production.json", "testdata/config.notExistent.json"}
+	if err := GetConfigLayers(files, &actual); err != nil {
+		t.Errorf("Error getting config. %s", err)
+	}
+	if actual != expected {
+		t.Errorf("Error getting config. Actual: %+v. Expected: %+v.", actual, expected)
+	}
+}
+
+func TestGetConfigLayersWrongBaseFile(t *testing.T) {
+	var actual config
+	files := []string{"testdata/configNotExistent.json", "testdata/config.production.json"}
+	err := GetConfigLayers(files, &actual)
+	if err == nil || err.Error() != "Error processing default configuration. open testdata/configNotExistent.json: no such file or directory" {
+		t.Errorf("Invalid error getting configuration. %s", err)
+	}
+}
+
+func TestGetConfigProfile(t *testing.T) {
+	os.Unsetenv("ADDRESS")
+	os.Unsetenv("LOG_LEVEL")
+	defer os.Unsetenv(ProfileEnvVar)
+	tests := []struct {
+		profile  string
+		expected config
+	}{
+		{"", config{Address: ":80", BasePath: "/users", LogLevel: "INFO", Realm: "es"}},
+		{"production", config{Address: ":8443", BasePath: "/users", LogLevel: "INFO", Realm: "uk"}},
+		{"production, local", config{Address: ":8443", BasePath: "/users", LogLevel: "INFO", Realm: "uk"}},
+	}
+	for _, test := range tests {
+		os.Setenv(ProfileEnvVar, test.profile)
+		var actual config
+		if err := GetConfigProfile("testdata/config.json", &actual); err != nil {
+			t.Errorf("Error getting config with profile %s. %s", test.profile, err)
+		}
+		if actual != test.expected {
+			t.Errorf("Error getting config with profile %s. Actual: %+v. Expected: %+v.", test.profile, actual, test.expected)
+		}
+	}
+}
+
+func TestConfigLoaderLayers(t *testing.T) {
+	loader := NewConfigLoader("config/config.yaml")
+	loader.AddProfile("production")
+	loader.AddLayer("config/override.json")
+	expected := []string{"config/config.yaml", "config/config.production.yaml", "config/override.json"}
+	if actual := loader.GetLayers(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Invalid layers. Actual: %v. Expected: %v", actual, expected)
+	}
+}
@@ -0,0 +1,418 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ByteSize is a configuration value with a number of bytes. It is decoded from a number or from a string
+// with a unit (e.g. "512", "64KB", "10MB" or "1.5GB"). Units are binary (1KB = 1024 bytes) and case-insensitive:
+// B, KB (or KiB), MB (or MiB), GB (or GiB) and TB (or TiB).
+type ByteSize int64
+
+// Byte size units.
+const (
+	Byte     ByteSize = 1
+	Kilobyte          = 1024 * Byte
+	Megabyte          = 1024 * Kilobyte
+	Gigabyte          = 1024 * Megabyte
+	Terabyte          = 1024 * Gigabyte
+)
+
+var byteSizeUnits = []struct {
+	name string
+	size ByteSize
+}{
+	{"TB", Terabyte},
+	{"GB", Gigabyte},
+	{"MB", Megabyte},
+	{"KB", Kilobyte},
+	{"B", Byte},
+}
+
+var byteSizeRegexp = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([a-zA-Z]*)$`)
+
+// ParseByteSize parses a byte size with an optional unit (e.g. "10MB").
+func ParseByteSize(s string) (ByteSize, error) {
+	match := byteSizeRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	unit := strings.ToUpper(match[2])
+	if len(unit) == 3 && unit[1] == 'I' {
+		unit = unit[:1] + "B"
+	}
+	size := Byte
+	if unit != "" {
+		found := false
+		for _, u := range byteSizeUnits {
+			if u.name == unit || (len(unit) == 1 && u.name[:1] == unit && u.size > Byte) {
+				size, found = u.size, true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid unit in byte size %q", s)
+		}
+	}
+	n, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	total := n * float64(size)
+	if total > math.MaxInt64 {
+		return 0, fmt.Errorf("byte size %q out of range", s)
+	}
+	return ByteSize(math.Round(total)), nil
+}
+
+// String formats the byte size with the largest unit that represents it exactly (e.g. "10MB").
+func (b ByteSize) String() string {
+	for _, u := range byteSizeUnits {
+		if b != 0 && b%u.size == 0 {
+			return strconv.FormatInt(int64(b/u.size), 10) + u.name
+		}
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+// MarshalText encodes the byte size as a string with unit.
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText decodes a byte size with an optional unit (e.g. from an environment variable).
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// UnmarshalJSON decodes a byte size from a JSON number (bytes) or a JSON string with unit.
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte{'"'}) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return b.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid byte size %s", data)
+	}
+	*b = ByteSize(n)
+	return nil
+}
+
+var (
+	byteSizeType = reflect.TypeOf(ByteSize(0))
+	urlType      = reflect.TypeOf(url.URL{})
+	regexpType   = reflect.TypeOf(regexp.Regexp{})
+)
+
+// isTextConfigType checks if a type is decoded from a string in the configuration files with setConfigValue
+// (as environment variables) instead of encoding/json. This way, the syntax is the same for both sources
+// (e.g. "5s" for a time.Duration).
+func isTextConfigType(t reflect.Type) bool {
+	t = derefType(t)
+	return t == durationType || t == byteSizeType || t == urlType || t == regexpType
+}
+
+// textConfigTypes caches if a type contains fields decoded as text.
+var textConfigTypes sync.Map
+
+// hasTextValues checks if a type contains fields decoded as text (directly or in nested structs, slices or maps).
+func hasTextValues(t reflect.Type) bool {
+	if cached, ok := textConfigTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	found := findTextValues(t, map[reflect.Type]bool{})
+	textConfigTypes.Store(t, found)
+	return found
+}
+
+func findTextValues(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if isTextConfigType(t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return findTextValues(t.Elem(), visited)
+	case reflect.Struct:
+		ptr := reflect.PtrTo(t)
+		if visited[t] || ptr.Implements(jsonUnmarshalerType) || ptr.Implements(textUnmarshalerType) {
+			return false
+		}
+		visited[t] = true
+		for _, field := range jsonFields(t) {
+			if findTextValues(field.Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stripTextValues returns a copy of a document value where the strings to be decoded as text are removed
+// (replaced by null) to be decoded by setTextValues after encoding/json.
+func stripTextValues(value interface{}, t reflect.Type) interface{} {
+	if !hasTextValues(t) {
+		return value
+	}
+	if isTextConfigType(t) {
+		if _, ok := value.(string); ok {
+			return nil
+		}
+		return value
+	}
+	t = derefType(t)
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		fields := jsonFields(t)
+		stripped := make(map[string]interface{}, len(object))
+		for key, v := range object {
+			if field, ok := lookupJSONField(fields, key); ok {
+				v = stripTextValues(v, field.Type)
+			}
+			stripped[key] = v
+		}
+		return stripped
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		stripped := make(map[string]interface{}, len(object))
+		for key, v := range object {
+			stripped[key] = stripTextValues(v, t.Elem())
+		}
+		return stripped
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+		stripped := make([]interface{}, len(items))
+		for i, item := range items {
+			stripped[i] = stripTextValues(item, t.Elem())
+		}
+		return stripped
+	}
+	return value
+}
+
+// setTextValues sets the values decoded as text from a document value (see stripTextValues).
+// It returns the path of the invalid value in case of error.
+func setTextValues(value interface{}, v reflect.Value, path string) (string, error) {
+	if !hasTextValues(v.Type()) {
+		return "", nil
+	}
+	if isTextConfigType(v.Type()) {
+		if s, ok := value.(string); ok {
+			return path, setConfigValue(v, s, "")
+		}
+		return "", nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if value == nil {
+				return "", nil
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", nil
+		}
+		fields := jsonFields(v.Type())
+		for _, key := range sortedKeys(object) {
+			field, ok := lookupJSONField(fields, key)
+			if !ok || !hasTextValues(field.Type) {
+				continue
+			}
+			fieldValue, _ := configFieldValue(v, field.Index, true)
+			if p, err := setTextValues(object[key], fieldValue, joinConfigPath(path, key)); err != nil {
+				return p, err
+			}
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok || v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return "", nil
+		}
+		for _, key := range sortedKeys(object) {
+			mapKey := reflect.ValueOf(key).Convert(v.Type().Key())
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if current := v.MapIndex(mapKey); current.IsValid() {
+				elem.Set(current)
+			}
+			if p, err := setTextValues(object[key], elem, joinConfigPath(path, key)); err != nil {
+				return p, err
+			}
+			v.SetMapIndex(mapKey, elem)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return "", nil
+		}
+		for i := 0; i < len(items) && i < v.Len(); i++ {
+			if p, err := setTextValues(items[i], v.Index(i), joinConfigPath(path, strconv.Itoa(i))); err != nil {
+				return p, err
+			}
+		}
+	}
+	return "", nil
+}
+
+// formatTextConfigValue returns the string of a value decoded as text (see isTextConfigType) with the syntax
+// accepted by setConfigValue (e.g. "5s" for a time.Duration).
+func formatTextConfigValue(v reflect.Value) string {
+	if v.Type() == durationType {
+		return fmt.Sprint(v.Interface())
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Interface().(fmt.Stringer).String()
+}
+
+// marshalConfig marshals a configuration to JSON like encoding/json, but the values decoded as text
+// (see isTextConfigType) are marshalled with the same syntax accepted by the loader (e.g. "5s" for a
+// time.Duration instead of a number of nanoseconds), so the output can be loaded again.
+func marshalConfig(config interface{}) ([]byte, error) {
+	data, err := json.Marshal(config)
+	if err != nil || config == nil {
+		return data, err
+	}
+	return marshalTextValues(data, reflect.ValueOf(config))
+}
+
+// marshalTextValues replaces the JSON of the values decoded as text in data (the JSON of v).
+func marshalTextValues(data []byte, v reflect.Value) ([]byte, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return data, nil
+		}
+		if isTextConfigType(v.Type()) {
+			break
+		}
+		v = v.Elem()
+	}
+	if !hasTextValues(v.Type()) {
+		return data, nil
+	}
+	if isTextConfigType(v.Type()) {
+		return json.Marshal(formatTextConfigValue(reflect.Indirect(v)))
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(v.Type())
+		return mapJSONObject(data, func(key string, value []byte) ([]byte, error) {
+			field, ok := lookupJSONField(fields, key)
+			if !ok {
+				return value, nil
+			}
+			fieldValue, err := v.FieldByIndexErr(field.Index)
+			if err != nil {
+				return value, nil
+			}
+			return marshalTextValues(value, fieldValue)
+		})
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return data, nil
+		}
+		return mapJSONObject(data, func(key string, value []byte) ([]byte, error) {
+			elem := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+			if !elem.IsValid() {
+				return value, nil
+			}
+			return marshalTextValues(value, elem)
+		})
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil || items == nil {
+			return data, nil
+		}
+		for i := 0; i < len(items) && i < v.Len(); i++ {
+			item, err := marshalTextValues(items[i], v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return json.Marshal(items)
+	}
+	return data, nil
+}
+
+// mapJSONObject replaces every value of a JSON object keeping the order of the keys.
+// Any other JSON value is returned unchanged.
+func mapJSONObject(data []byte, mapValue func(key string, value []byte) ([]byte, error)) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if value, err = mapValue(key, value); err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		keyJSON, _ := json.Marshal(key)
+		buf.Write(keyJSON)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,306 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"encoding/json"
+	"flag"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected ByteSize
+		valid    bool
+	}{
+		{"512", 512, true},
+		{"512B", 512, true},
+		{"64KB", 64 * Kilobyte, true},
+		{"64kb", 64 * Kilobyte, true},
+		{"10MB", 10 * Megabyte, true},
+		{"10MiB", 10 * Megabyte, true},
+		{"10 M", 10 * Megabyte, true},
+		{"1.5GB", 1536 * Megabyte, true},
+		{"2TB", 2 * Terabyte, true},
+		{"10XB", 0, false},
+		{"MB", 0, false},
+		{"-1MB", 0, false},
+		{"99999999999TB", 0, false},
+	}
+	for _, tt := range tests {
+		actual, err := ParseByteSize(tt.s)
+		if tt.valid != (err == nil) {
+			t.Errorf("Invalid error parsing %s. Error: %v", tt.s, err)
+		} else if actual != tt.expected {
+			t.Errorf("Invalid byte size for %s. Actual: %d. Expected: %d", tt.s, actual, tt.expected)
+		}
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	tests := []struct {
+		size     ByteSize
+		expected string
+	}{
+		{0, "0B"},
+		{1000, "1000B"},
+		{64 * Kilobyte, "64KB"},
+		{1536 * Megabyte, "1536MB"},
+		{3 * Gigabyte, "3GB"},
+	}
+	for _, tt := range tests {
+		if actual := tt.size.String(); actual != tt.expected {
+			t.Errorf("Invalid string for %d. Actual: %s. Expected: %s", tt.size, actual, tt.expected)
+		}
+	}
+	data, err := json.Marshal(struct {
+		Size ByteSize `json:"size"`
+	}{10 * Megabyte})
+	if err != nil || string(data) != `{"size":"10MB"}` {
+		t.Errorf("Invalid JSON. Actual: %s. Error: %v", data, err)
+	}
+}
+
+type typesConfig struct {
+	Timeout   time.Duration            `json:"timeout" env:"TEST_TIMEOUT"`
+	Timeouts  map[string]time.Duration `json:"timeouts"`
+	Retries   []time.Duration          `json:"retries"`
+	MaxBody   ByteSize                 `json:"maxBody" env:"TEST_MAX_BODY"`
+	MaxFile   ByteSize                 `json:"maxFile"`
+	Endpoint  *url.URL                 `json:"endpoint" env:"TEST_ENDPOINT"`
+	Upstream  url.URL                  `json:"upstream"`
+	Path      *regexp.Regexp           `json:"path" env:"TEST_PATH"`
+	Listen    net.IP                   `json:"listen" env:"TEST_LISTEN"`
+	Nanos     time.Duration            `json:"nanos"`
+	Backends  []typesBackend           `json:"backends"`
+	Unchanged *url.URL                 `json:"unchanged"`
+}
+
+type typesBackend struct {
+	URL     *url.URL      `json:"url"`
+	Timeout time.Duration `json:"timeout"`
+}
+
+func TestLoadConfigTypes(t *testing.T) {
+	file := writeTestFile(t, "config.json", `{
+  "timeout": "5s",
+  "timeouts": {"read": "1s", "write": "1m"},
+  "retries": ["100ms", "1s"],
+  "maxBody": "10MB",
+  "maxFile": 1024,
+  "endpoint": "http://localhost:8080/api",
+  "upstream": "https://example.com",
+  "path": "^/users/[a-z]+$",
+  "listen": "127.0.0.1",
+  "nanos": 1000,
+  "backends": [{"url": "http://backend", "timeout": "2s"}]
+}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	for _, name := range []string{"TEST_TIMEOUT", "TEST_MAX_BODY", "TEST_ENDPOINT", "TEST_PATH", "TEST_LISTEN"} {
+		os.Unsetenv(name)
+	}
+	var cfg typesConfig
+	if err := GetConfig(file, &cfg); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+	if cfg.Timeout != 5*time.Second || cfg.Nanos != 1000 || cfg.MaxBody != 10*Megabyte || cfg.MaxFile != Kilobyte {
+		t.Errorf("Invalid durations or sizes. Actual: %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Timeouts, map[string]time.Duration{"read": time.Second, "write": time.Minute}) ||
+		!reflect.DeepEqual(cfg.Retries, []time.Duration{100 * time.Millisecond, time.Second}) {
+		t.Errorf("Invalid duration collections. Actual: %+v", cfg)
+	}
+	if cfg.Endpoint == nil || cfg.Endpoint.Host != "localhost:8080" || cfg.Upstream.Host != "example.com" {
+		t.Errorf("Invalid URLs. Actual: %+v", cfg)
+	}
+	if cfg.Path == nil || !cfg.Path.MatchString("/users/john") || !cfg.Listen.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("Invalid regexp or IP. Actual: %+v", cfg)
+	}
+	if len(cfg.Backends) != 1 || cfg.Backends[0].URL.Host != "backend" || cfg.Backends[0].Timeout != 2*time.Second {
+		t.Errorf("Invalid backends. Actual: %+v", cfg.Backends)
+	}
+	if cfg.Unchanged != nil {
+		t.Errorf("Unexpected URL. Actual: %s", cfg.Unchanged)
+	}
+
+	// Environment variables use the same syntax
+	env := map[string]string{
+		"TEST_TIMEOUT":  "1m",
+		"TEST_MAX_BODY": "1GB",
+		"TEST_ENDPOINT": "http://remote/api",
+		"TEST_PATH":     "^/$",
+		"TEST_LISTEN":   "::1",
+	}
+	for name, value := range env {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+	if err := GetConfig(file, &cfg); err != nil {
+		t.Fatalf("Error loading config with env. %s", err)
+	}
+	if cfg.Timeout != time.Minute || cfg.MaxBody != Gigabyte || cfg.Endpoint.Host != "remote" ||
+		cfg.Path.String() != "^/$" || !cfg.Listen.Equal(net.IPv6loopback) {
+		t.Errorf("Invalid config from env. Actual: %+v", cfg)
+	}
+}
+
+func TestLoadConfigTypesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"duration", "{\n  \"timeout\": \"5 seconds\"\n}", `:2:3: key "timeout": time: unknown unit " seconds" in duration "5 seconds"`},
+		{"nested duration", "{\n  \"backends\": [{\"timeout\": \"x\"}]\n}", `:2:3: key "backends.0.timeout": time: invalid duration "x"`},
+		{"byte size", "{\n  \"maxBody\": \"10XB\"\n}", `:2:3: key "maxBody": invalid unit in byte size "10XB"`},
+		{"regexp", "{\n  \"path\": \"(\"\n}", `:2:3: key "path": error parsing regexp: missing closing ): ` + "`(`"},
+	}
+	for _, name := range []string{"TEST_TIMEOUT", "TEST_MAX_BODY", "TEST_ENDPOINT", "TEST_PATH", "TEST_LISTEN"} {
+		os.Unsetenv(name)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeTestFile(t, "config.json", tt.content)
+			defer os.RemoveAll(filepath.Dir(file))
+			var cfg typesConfig
+			err := GetConfig(file, &cfg)
+			if err == nil || !strings.HasSuffix(err.Error(), tt.expected) {
+				t.Errorf("Invalid error. Actual: %v. Expected suffix: %s", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConfigTypesRoundTrip(t *testing.T) {
+	for _, name := range []string{"TEST_TIMEOUT", "TEST_MAX_BODY", "TEST_ENDPOINT", "TEST_PATH", "TEST_LISTEN"} {
+		os.Unsetenv(name)
+	}
+	var cfg typesConfig
+	if err := GetConfig("testdata/types.json", &cfg); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+	data, err := marshalConfig(&cfg)
+	if err != nil {
+		t.Fatalf("Error marshalling config. %s", err)
+	}
+	expected := `{"timeout":"5s","timeouts":{"read":"1s","write":"1m0s"},"retries":["100ms","1s"],"maxBody":"10MB",` +
+		`"maxFile":"1KB","endpoint":"http://localhost:8080/api","upstream":"https://example.com","path":"^/users/[a-z]+$",` +
+		`"listen":"127.0.0.1","nanos":"1µs","backends":[{"url":"http://backend","timeout":"2s"}],"unchanged":null}`
+	if string(data) != expected {
+		t.Errorf("Invalid marshalled config. Actual: %s. Expected: %s", data, expected)
+	}
+
+	// The marshalled configuration is valid according to the generated schema
+	schema, err := GenerateSchema(&cfg)
+	if err != nil {
+		t.Fatalf("Error generating schema. %s", err)
+	}
+	schemaFile := writeTestFile(t, "types.json", string(schema))
+	defer os.RemoveAll(filepath.Dir(schemaFile))
+	validator := NewValidator()
+	if err := validator.LoadSchemas(filepath.Dir(schemaFile)); err != nil {
+		t.Fatalf("Error loading generated schema. %s", err)
+	}
+	if err := validator.ValidateBytes("types", data, &map[string]interface{}{}); err != nil {
+		t.Errorf("Marshalled config is not valid according to the generated schema. %s", err)
+	}
+	if err := validator.ValidateConfig("types", &cfg); err != nil {
+		t.Errorf("Config is not valid according to the generated schema. %s", err)
+	}
+
+	// The marshalled configuration is loaded again with the same values
+	file := writeTestFile(t, "config.json", string(data))
+	defer os.RemoveAll(filepath.Dir(file))
+	var loaded typesConfig
+	if err := GetConfig(file, &loaded); err != nil {
+		t.Fatalf("Error loading marshalled config. %s", err)
+	}
+	if reloaded, _ := marshalConfig(&loaded); string(reloaded) != string(data) {
+		t.Errorf("Invalid reloaded config. Actual: %s. Expected: %s", reloaded, data)
+	}
+
+	// MarshalRedacted (used by the logger) keeps the output of encoding/json
+	if redacted, _ := MarshalRedacted(&cfg); !strings.HasPrefix(string(redacted), `{"timeout":5000000000,`) {
+		t.Errorf("Invalid redacted config. Actual: %s", redacted)
+	}
+}
+
+type urlConfig struct {
+	U *url.URL `json:"u"`
+}
+
+func TestConfigTypesAreValues(t *testing.T) {
+	os.Unsetenv("P_U")
+	file := writeTestFile(t, "config.json", `{"u":"http://file/"}`)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	// A URL is bound to a single flag
+	var cfg urlConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if _, err := BindConfigFlags(fs, &cfg); err != nil {
+		t.Fatalf("Error binding flags. %s", err)
+	}
+	if fs.Lookup("u") == nil {
+		t.Errorf("Flag --u is not defined")
+	}
+
+	// A URL is documented as a single field
+	doc, err := GetConfigDoc(&urlConfig{}, file)
+	if err != nil {
+		t.Fatalf("Error documenting config. %s", err)
+	}
+	if len(doc) != 1 || doc[0].Key != "u" || doc[0].Default != "http://file/" {
+		t.Errorf("Invalid doc of URL field. Actual: %+v", doc)
+	}
+
+	// A URL is set by the file
+	loader := NewConfigLoader(file)
+	loader.SetEnvPrefix("P_")
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+	if cfg.U.String() != "http://file/" {
+		t.Errorf("Invalid URL from file. Actual: %s", cfg.U)
+	}
+	if source := loader.GetProvenance().Get("u"); source.Source != ConfigSourceFile {
+		t.Errorf("Invalid source for u. Actual: %s", source)
+	}
+
+	// A URL is set by a derived environment variable
+	os.Setenv("P_U", "http://a/b")
+	defer os.Unsetenv("P_U")
+	cfg = urlConfig{}
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("Error loading config. %s", err)
+	}
+	if cfg.U.String() != "http://a/b" {
+		t.Errorf("Invalid URL from environment. Actual: %s", cfg.U)
+	}
+	if source := loader.GetProvenance().Get("u"); source.String() != "env:P_U" {
+		t.Errorf("Invalid source for u. Actual: %s", source)
+	}
+}
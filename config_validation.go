@@ -0,0 +1,116 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ConfigViolation is a violation of the JSON schema by a configuration field.
+// Source identifies the source that set the invalid value.
+type ConfigViolation struct {
+	Field       string
+	Description string
+	Source      ConfigSource
+}
+
+func (v ConfigViolation) String() string {
+	return fmt.Sprintf("%s: %s (%s)", v.Field, v.Description, v.Source)
+}
+
+// ConfigValidationError is an error with all the violations of the JSON schema by the configuration.
+type ConfigValidationError struct {
+	Violations []ConfigViolation
+}
+
+func (e *ConfigValidationError) Error() string {
+	violations := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		violations[i] = violation.String()
+	}
+	return "Invalid configuration according to JSON schema: " + strings.Join(violations, "; ")
+}
+
+// LoadValid loads the configuration (see Load) and validates it against the JSON schema schemaName.
+// If the configuration is invalid, it returns a *ConfigValidationError with every violation (sorted by field)
+// and the source of the invalid value (e.g. the file or the environment variable).
+func (c *ConfigLoader) LoadValid(config interface{}, validator *Validator, schemaName string) error {
+	if err := c.Load(config); err != nil {
+		return err
+	}
+	return c.validate(config, validator, schemaName)
+}
+
+// validate validates the configuration, already loaded, against the JSON schema schemaName.
+func (c *ConfigLoader) validate(config interface{}, validator *Validator, schemaName string) error {
+	data, err := marshalConfig(config)
+	if err != nil {
+		return fmt.Errorf("Invalid configuration according to JSON schema: %s", err)
+	}
+	result, err := validator.validateResult(schemaName, gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return fmt.Errorf("Invalid configuration according to JSON schema: %s", err)
+	}
+	if result.Valid() {
+		return nil
+	}
+	provenance := c.GetProvenance()
+	validationErr := &ConfigValidationError{}
+	for _, resultErr := range result.Errors() {
+		field := resultErr.Field()
+		if field == gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+			field = ""
+		}
+		// Errors about a property (e.g. required) are assigned to the property
+		if property, ok := resultErr.Details()["property"].(string); ok {
+			field = joinConfigPath(field, property)
+		}
+		validationErr.Violations = append(validationErr.Violations, ConfigViolation{
+			Field:       field,
+			Description: resultErr.Description(),
+			Source:      provenance.Get(field),
+		})
+	}
+	// The violations are sorted because the JSON schema library reports them in map order
+	sort.Slice(validationErr.Violations, func(i, j int) bool {
+		vi, vj := validationErr.Violations[i], validationErr.Violations[j]
+		if vi.Field != vj.Field {
+			return vi.Field < vj.Field
+		}
+		return vi.Description < vj.Description
+	})
+	return validationErr
+}
+
+// GetValidConfig loads the configuration (see GetConfig) and validates it against the JSON schema schemaName
+// stored in the schemasDir directory. It returns the validator with all the JSON schemas loaded to be reused
+// (e.g. to validate the requests).
+func GetValidConfig(configFile, schemasDir, schemaName string, config interface{}) (*Validator, error) {
+	validator := NewValidator()
+	if err := validator.LoadSchemas(schemasDir); err != nil {
+		return nil, err
+	}
+	if err := NewConfigLoader(configFile).LoadValid(config, validator, schemaName); err != nil {
+		return nil, err
+	}
+	return validator, nil
+}
@@ -0,0 +1,91 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetValidConfig(t *testing.T) {
+	os.Unsetenv("ADDRESS")
+	os.Unsetenv("LOG_LEVEL")
+	var cfg config
+	validator, err := GetValidConfig("testdata/config.json", "testdata/schemas", "config", &cfg)
+	if err != nil {
+		t.Fatalf("Error getting valid config. %s", err)
+	}
+	if validator == nil || cfg.Realm != "es" {
+		t.Errorf("Invalid config or validator. Config: %+v", cfg)
+	}
+	if _, err := GetValidConfig("testdata/config.json", "testdata/notExistent", "config", &cfg); err == nil {
+		t.Errorf("Expected error with a wrong schemas directory")
+	}
+}
+
+func TestLoadValidViolations(t *testing.T) {
+	file := writeTestFile(t, "config.json", `{"address": "localhost", "basePath": "/users", "realm": "xx"}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	os.Unsetenv("ADDRESS")
+	os.Setenv("LOG_LEVEL", "TRACE")
+	defer os.Unsetenv("LOG_LEVEL")
+	validator := NewValidator()
+	if err := validator.LoadSchemas("testdata/schemas"); err != nil {
+		t.Fatalf("Error loading schemas. %s", err)
+	}
+	var cfg config
+	err := NewConfigLoader(file).LoadValid(&cfg, validator, "config")
+	validationErr, ok := err.(*ConfigValidationError)
+	if !ok {
+		t.Fatalf("Invalid error type. Actual: %v", err)
+	}
+	sources := map[string]ConfigSource{}
+	for _, violation := range validationErr.Violations {
+		sources[violation.Field] = violation.Source
+	}
+	expected := map[string]ConfigSource{
+		"address":  {Path: "address", Source: ConfigSourceFile, Origin: file},
+		"logLevel": {Path: "logLevel", Source: ConfigSourceEnv, Origin: "LOG_LEVEL"},
+		"realm":    {Path: "realm", Source: ConfigSourceFile, Origin: file},
+	}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Invalid violations. Actual: %+v. Expected: %+v", sources, expected)
+	}
+}
+
+func TestLoadValidRequired(t *testing.T) {
+	type requiredConfig struct {
+		Address string `json:"address,omitempty"`
+	}
+	schemas := writeTestFile(t, "required.json", `{"type": "object", "required": ["address"]}`)
+	defer os.RemoveAll(filepath.Dir(schemas))
+	validator := NewValidator()
+	if err := validator.LoadSchemas(filepath.Dir(schemas)); err != nil {
+		t.Fatalf("Error loading schemas. %s", err)
+	}
+	file := writeTestFile(t, "config.json", `{"realm": "es"}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	var cfg requiredConfig
+	err := NewConfigLoader(file).LoadValid(&cfg, validator, "required")
+	expected := "Invalid configuration according to JSON schema: address: address is required (default)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Invalid error. Actual: %v. Expected: %s", err, expected)
+	}
+}
@@ -0,0 +1,274 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ConfigReloadAlarm is the alarm identifier logged when a configuration reload is rejected.
+var ConfigReloadAlarm = "ALARM_CONFIG_RELOAD"
+
+// DefaultConfigWatchInterval is the interval to check the configuration files when Watch is called
+// with a non-positive interval.
+var DefaultConfigWatchInterval = 10 * time.Second
+
+// ConfigWatcher reloads the configuration when the configuration files change (or when the process receives
+// a SIGHUP signal), and publishes every new valid snapshot to the subscribers. If the new configuration is
+// invalid, it is rejected (logging an alarm) and the last valid configuration is kept.
+type ConfigWatcher struct {
+	loader      *ConfigLoader
+	defaults    reflect.Value
+	validator   *Validator
+	schemaName  string
+	logger      *Logger
+	current     atomic.Value
+	mutex       sync.Mutex
+	reloadMutex sync.Mutex
+	subscribers []func(config interface{})
+	modTimes    map[string]time.Time
+	stop        chan struct{}
+}
+
+// NewConfigWatcher creates a ConfigWatcher and loads the initial configuration. The config parameter must be
+// a pointer to a struct. Its values are used as defaults for every reload.
+func NewConfigWatcher(loader *ConfigLoader, config interface{}) (*ConfigWatcher, error) {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Error processing configuration. Expected a pointer to a struct")
+	}
+	w := &ConfigWatcher{
+		loader:   loader,
+		defaults: copyConfigValue(v.Elem()),
+		logger:   NewLogger(),
+	}
+	w.modTimes = w.getModTimes()
+	snapshot, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	v.Elem().Set(reflect.ValueOf(snapshot).Elem())
+	w.current.Store(snapshot)
+	return w, nil
+}
+
+// SetValidator sets a validator to check every configuration snapshot against a JSON schema.
+// It also validates the current configuration.
+func (w *ConfigWatcher) SetValidator(validator *Validator, schemaName string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.validator = validator
+	w.schemaName = schemaName
+	return validator.ValidateConfig(schemaName, w.Config())
+}
+
+// SetLogger sets the logger to report the reloads.
+func (w *ConfigWatcher) SetLogger(logger *Logger) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.logger = logger
+}
+
+// Config returns the current configuration snapshot (a pointer to the configuration struct).
+// Snapshots must be handled as read-only values because they are shared.
+func (w *ConfigWatcher) Config() interface{} {
+	return w.current.Load()
+}
+
+// Subscribe registers a function that is called with every new configuration snapshot.
+func (w *ConfigWatcher) Subscribe(subscriber func(config interface{})) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.subscribers = append(w.subscribers, subscriber)
+}
+
+// Reload loads and validates the configuration. If it is valid, it is published to the subscribers and
+// the changes are logged (see DiffConfig).
+// Otherwise, the error is logged with an alarm and returned, keeping the last valid configuration.
+// The reloads are serialized, so the subscribers receive the snapshots in order. The subscribers may use the
+// watcher (e.g. Config or Subscribe) except Reload.
+func (w *ConfigWatcher) Reload() error {
+	w.reloadMutex.Lock()
+	defer w.reloadMutex.Unlock()
+	w.mutex.Lock()
+	logger := w.logger
+	snapshot, err := w.load()
+	if err != nil {
+		w.mutex.Unlock()
+		logger.ErrorC(&LogContext{Alarm: ConfigReloadAlarm}, "Configuration reload rejected. %s", err)
+		return err
+	}
+	previous := w.current.Load()
+	w.current.Store(snapshot)
+	subscribers := make([]func(config interface{}), len(w.subscribers))
+	copy(subscribers, w.subscribers)
+	w.mutex.Unlock()
+
+	if diff, err := DiffConfig(previous, snapshot); err == nil {
+		diff.Log(logger, "Configuration reloaded")
+	} else {
+		logger.Info("Configuration reloaded")
+	}
+	for _, subscriber := range subscribers {
+		subscriber(snapshot)
+	}
+	return nil
+}
+
+func (w *ConfigWatcher) load() (interface{}, error) {
+	snapshot := reflect.New(w.defaults.Type())
+	snapshot.Elem().Set(copyConfigValue(w.defaults))
+	config := snapshot.Interface()
+	if w.validator != nil {
+		if err := w.loader.LoadValid(config, w.validator, w.schemaName); err != nil {
+			return nil, err
+		}
+	} else if err := w.loader.Load(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Watch starts watching the configuration files (checking their modification time every interval)
+// and the SIGHUP signal to reload the configuration (note that RotatingFile.ReopenOnSignal with SIGHUP would also
+// reopen the log file on every reload). A non-positive interval is replaced by
+// DefaultConfigWatchInterval. Call Stop to finish watching.
+func (w *ConfigWatcher) Watch(interval time.Duration) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.stop != nil {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultConfigWatchInterval
+	}
+	stop := make(chan struct{})
+	w.stop = stop
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-signals:
+				w.updateModTimes()
+				w.Reload()
+			case <-ticker.C:
+				if w.updateModTimes() {
+					w.Reload()
+				}
+			}
+		}
+	}()
+}
+
+// Stop finishes watching the configuration.
+func (w *ConfigWatcher) Stop() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+}
+
+// updateModTimes refreshes the modification time of the configuration files, and returns true if any of them changed.
+func (w *ConfigWatcher) updateModTimes() bool {
+	modTimes := w.getModTimes()
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if reflect.DeepEqual(modTimes, w.modTimes) {
+		return false
+	}
+	w.modTimes = modTimes
+	return true
+}
+
+// getModTimes returns the modification time of the configuration files (including the .env file). Missing files are also included
+// (with zero time) to detect when an optional layer is created.
+func (w *ConfigWatcher) getModTimes() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	files := w.loader.GetLayers()
+	if w.loader.dotEnvFile != "" {
+		files = append(files, w.loader.dotEnvFile)
+	}
+	for _, file := range files {
+		if w.loader.isRemoteLayer(file) {
+			continue
+		}
+		var modTime time.Time
+		if info, err := os.Stat(file); err == nil {
+			modTime = info.ModTime()
+		}
+		modTimes[file] = modTime
+	}
+	return modTimes
+}
+
+// copyConfigValue returns a deep copy of a configuration value (following pointers, slices and maps)
+// to avoid sharing data between snapshots.
+func copyConfigValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(copyConfigValue(v.Elem()))
+		return p
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyConfigValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			c.SetMapIndex(key, copyConfigValue(v.MapIndex(key)))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				c.Field(i).Set(copyConfigValue(v.Field(i)))
+			}
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,219 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	buf   bytes.Buffer
+	mutex sync.Mutex
+}
+
+func newLockedBuffer() *lockedBuffer {
+	return &lockedBuffer{}
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+func writeWatchedConfig(t *testing.T, file string, content string, modTime time.Time) {
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("Error writing config file. %s", err)
+	}
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatalf("Error changing modification time. %s", err)
+	}
+}
+
+func newTestWatcher(t *testing.T, content string) (*ConfigWatcher, string, *lockedBuffer) {
+	os.Unsetenv("ADDRESS")
+	os.Unsetenv("LOG_LEVEL")
+	file := writeTestFile(t, "config.json", content)
+	out := newLockedBuffer()
+	cfg := config{BasePath: "/default"}
+	watcher, err := NewConfigWatcher(NewConfigLoader(file), &cfg)
+	if err != nil {
+		t.Fatalf("Error creating watcher. %s", err)
+	}
+	watcher.SetLogger(&Logger{out: out, logLevel: infoLevel})
+	if cfg.Address != ":80" || cfg.BasePath != "/default" {
+		t.Errorf("Invalid initial config. Actual: %+v", cfg)
+	}
+	return watcher, file, out
+}
+
+func TestConfigWatcherReload(t *testing.T) {
+	watcher, file, out := newTestWatcher(t, `{"address": ":80", "logLevel": "INFO", "realm": "es"}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	validator := NewValidator()
+	if err := validator.LoadSchemas("testdata/schemas"); err != nil {
+		t.Fatalf("Error loading schemas. %s", err)
+	}
+	if err := watcher.SetValidator(validator, "config"); err != nil {
+		t.Fatalf("Error validating initial config. %s", err)
+	}
+	var published *config
+	watcher.Subscribe(func(cfg interface{}) {
+		published = cfg.(*config)
+	})
+
+	// Valid configuration
+	writeWatchedConfig(t, file, `{"address": ":8080", "logLevel": "DEBUG", "realm": "es"}`, time.Now())
+	if err := watcher.Reload(); err != nil {
+		t.Fatalf("Error reloading config. %s", err)
+	}
+	expected := config{Address: ":8080", BasePath: "/default", LogLevel: "DEBUG", Realm: "es"}
+	if published == nil || *published != expected {
+		t.Errorf("Invalid published config. Actual: %+v. Expected: %+v", published, expected)
+	}
+	if current := watcher.Config().(*config); *current != expected {
+		t.Errorf("Invalid current config. Actual: %+v. Expected: %+v", current, expected)
+	}
+	if !strings.Contains(out.String(), `"configChanges":[{"path":"address","old":":80","new":":8080"},{"path":"logLevel","old":"INFO","new":"DEBUG"}],"msg":"Configuration reloaded"`) {
+		t.Errorf("Expected configuration changes in reload log. Log: %s", out.String())
+	}
+
+	// Invalid configurations (by syntax or by schema) are rejected
+	for _, content := range []string{`{"address": `, `{"address": ":8080", "logLevel": "TRACE", "realm": "es"}`} {
+		published = nil
+		writeWatchedConfig(t, file, content, time.Now())
+		if err := watcher.Reload(); err == nil {
+			t.Errorf("Expected error reloading invalid config: %s", content)
+		}
+		if published != nil {
+			t.Errorf("Invalid config must not be published")
+		}
+		if current := watcher.Config().(*config); *current != expected {
+			t.Errorf("Invalid current config after rejection. Actual: %+v. Expected: %+v", current, expected)
+		}
+	}
+	if !strings.Contains(out.String(), `"alarm":"ALARM_CONFIG_RELOAD"`) {
+		t.Errorf("Expected alarm for rejected reload. Log: %s", out.String())
+	}
+}
+
+func TestConfigWatcherWatch(t *testing.T) {
+	watcher, file, _ := newTestWatcher(t, `{"address": ":80"}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	published := make(chan *config, 1)
+	watcher.Subscribe(func(cfg interface{}) {
+		published <- cfg.(*config)
+	})
+	watcher.Watch(10 * time.Millisecond)
+	defer watcher.Stop()
+
+	writeWatchedConfig(t, file, `{"address": ":8080"}`, time.Now().Add(time.Minute))
+	select {
+	case cfg := <-published:
+		if cfg.Address != ":8080" {
+			t.Errorf("Invalid reloaded config. Actual: %+v", cfg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Configuration not reloaded after changing the file")
+	}
+}
+
+func TestConfigWatcherSubscriberUsesWatcher(t *testing.T) {
+	watcher, file, out := newTestWatcher(t, `{"address": ":80"}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	calls := 0
+	watcher.Subscribe(func(cfg interface{}) {
+		// Subscribers are called without the lock, so they can use the watcher
+		calls++
+		watcher.SetLogger(&Logger{out: out, logLevel: infoLevel})
+		watcher.Subscribe(func(cfg interface{}) {})
+	})
+	if err := watcher.Reload(); err != nil {
+		t.Fatalf("Error reloading config. %s", err)
+	}
+	if calls != 1 {
+		t.Errorf("Invalid number of subscriber calls. Actual: %d. Expected: 1", calls)
+	}
+}
+
+func TestConfigWatcherConcurrentReloads(t *testing.T) {
+	watcher, file, _ := newTestWatcher(t, `{"address": ":80"}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	var mutex sync.Mutex
+	var last interface{}
+	watcher.Subscribe(func(cfg interface{}) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		last = cfg
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			watcher.Reload()
+		}()
+	}
+	wg.Wait()
+	// The last published snapshot is the current one
+	if last != watcher.Config() {
+		t.Errorf("Subscriber received an older snapshot than the current one")
+	}
+}
+
+func TestNewConfigWatcherErrors(t *testing.T) {
+	var cfg config
+	if _, err := NewConfigWatcher(NewConfigLoader("testdata/configNotExistent.json"), &cfg); err == nil {
+		t.Errorf("Expected error with a non-existent config file")
+	}
+	if _, err := NewConfigWatcher(NewConfigLoader("testdata/config.json"), cfg); err == nil {
+		t.Errorf("Expected error with a non-pointer config")
+	}
+}
+
+func TestCopyConfigValue(t *testing.T) {
+	type nested struct {
+		Values []string          `json:"values"`
+		Labels map[string]string `json:"labels"`
+	}
+	type copyConfig struct {
+		Nested *nested `json:"nested"`
+	}
+	orig := copyConfig{Nested: &nested{Values: []string{"a"}, Labels: map[string]string{"k": "v"}}}
+	c := copyConfigValue(reflect.ValueOf(orig)).Interface().(copyConfig)
+	c.Nested.Values[0] = "b"
+	c.Nested.Labels["k"] = "w"
+	if orig.Nested.Values[0] != "a" || orig.Nested.Labels["k"] != "v" {
+		t.Errorf("Copy shares data with the original value. Original: %+v", orig.Nested)
+	}
+}
@@ -18,8 +18,8 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
+	"fmt"
 	"net/http"
 	"os"
 	"time"
@@ -29,9 +29,10 @@ import (
 )
 
 type config struct {
-	Address  string `json:"address" env:"ADDRESS"`
-	BasePath string `json:"basePath" env:"BASE_PATH"`
-	LogLevel string `json:"logLevel" env:"LOG_LEVEL"`
+	Address   string `json:"address" env:"ADDRESS" desc:"Listening address"`
+	BasePath  string `json:"basePath" env:"BASE_PATH" desc:"Base path of the API"`
+	LogLevel  string `json:"logLevel" env:"LOG_LEVEL" desc:"Log level"`
+	LogFormat string `json:"logFormat" env:"LOG_FORMAT" desc:"Log format: json, logfmt or console"`
 }
 
 func withMws(op string) func(http.HandlerFunc) http.HandlerFunc {
@@ -55,32 +56,48 @@ func main() {
 	logger.SetLogContext(&logContext)
 	alarmContext := &govice.LogContext{Alarm: "ALARM_INIT"}
 
-	// Prepare the configuration
-	cfgFile := flag.String("config", "./config.json", "path to config file")
-	flag.Parse()
+	// Prepare the configuration and validate it against the JSON schema
 	var cfg config
-	if err := govice.GetConfig(*cfgFile, &cfg); err != nil {
-		logger.FatalC(alarmContext, "Bad configuration with file '%s'. %s", *cfgFile, err)
+	cfgFile := flag.String("config", "./config.json", "path to config file")
+	dotEnvFile := flag.String("env", "./.env", "path to .env file with environment variables (optional)")
+	check := flag.Bool("check", false, "check the configuration and exit")
+	cfgFlags, err := govice.BindConfigFlags(flag.CommandLine, &cfg)
+	if err != nil {
+		logger.FatalC(alarmContext, "%s", err)
 		os.Exit(1)
 	}
-	logger.SetLevel(cfg.LogLevel)
-	govice.SetDefaultLogLevel(cfg.LogLevel)
-
-	// Log the configuration
-	if configBytes, err := json.Marshal(cfg); err == nil {
-		logger.Info("Configuration: %s", string(configBytes))
-	}
-
-	// Create the validator and validate the configuration
+	flag.Parse()
+	defaults := cfg
 	validator := govice.NewValidator()
 	if err := validator.LoadSchemas("schemas"); err != nil {
 		logger.FatalC(alarmContext, "Error loading JSON schemas for validator. %s", err)
 		os.Exit(1)
 	}
-	if err := validator.ValidateConfig("config", &cfg); err != nil {
-		logger.FatalC(alarmContext, "Bad configuration according to JSON schema. %s", err)
+	cfgLoader := govice.NewConfigLoader(*cfgFile)
+	cfgLoader.SetFlags(cfgFlags)
+	cfgLoader.SetDotEnvFile(*dotEnvFile)
+	if *check {
+		// Report every problem of the configuration (e.g. in the entrypoint of a container)
+		if err := cfgLoader.Check(&cfg, "schemas", "config"); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if err := cfgLoader.LoadValid(&cfg, validator, "config"); err != nil {
+		logger.FatalC(alarmContext, "Bad configuration with file '%s'. %s", *cfgFile, err)
 		os.Exit(1)
 	}
+	logger.SetLevel(cfg.LogLevel)
+	govice.SetDefaultLogLevel(cfg.LogLevel)
+	logger.SetFormat(cfg.LogFormat)
+	govice.SetDefaultLogFormat(cfg.LogFormat)
+
+	// Log the configuration (the values changed from the defaults) and the source of every value
+	if diff, err := govice.DiffConfig(defaults, cfg); err == nil {
+		diff.Log(logger, "Configuration loaded")
+	}
+	cfgLoader.GetProvenance().Log(logger)
 
 	// Create the logic of the service
 	u := NewUsersService(validator)
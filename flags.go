@@ -0,0 +1,137 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync/atomic"
+)
+
+// FlagConfig is the configuration of a feature flag. A disabled flag is off for every request. An enabled flag
+// is on for a percentage of the users (all of them if Percentage is not set). The per-realm overrides replace
+// the whole configuration of the flag for the requests of a realm.
+type FlagConfig struct {
+	Enabled    bool                  `json:"enabled"`
+	Percentage *float64              `json:"percentage,omitempty"`
+	Realms     map[string]FlagConfig `json:"realms,omitempty"`
+}
+
+// FlagsConfig is the configuration section with the feature flags indexed by name.
+type FlagsConfig map[string]FlagConfig
+
+// FlagsLogContext is a complementary log context with the evaluation of the feature flags for a request.
+type FlagsLogContext struct {
+	Flags map[string]bool `json:"flags,omitempty"`
+}
+
+// Flags evaluates feature flags. The configuration is replaced atomically with Update, so the flags can be
+// evaluated concurrently from the request handlers.
+type Flags struct {
+	config atomic.Value
+}
+
+// NewFlags creates the feature flags with a configuration section.
+func NewFlags(config FlagsConfig) *Flags {
+	f := &Flags{}
+	f.Update(config)
+	return f
+}
+
+// WatchFlags creates the feature flags with the configuration section of the current snapshot of a
+// ConfigWatcher, and refreshes them with every new snapshot. The section function returns the flags section
+// of a snapshot (a pointer to the configuration struct).
+func WatchFlags(watcher *ConfigWatcher, section func(config interface{}) FlagsConfig) *Flags {
+	f := NewFlags(section(watcher.Config()))
+	watcher.Subscribe(func(config interface{}) {
+		f.Update(section(config))
+	})
+	return f
+}
+
+// Update replaces the configuration of the feature flags.
+func (f *Flags) Update(config FlagsConfig) {
+	flags := make(FlagsConfig, len(config))
+	for name, flag := range config {
+		flags[name] = flag
+	}
+	f.config.Store(flags)
+}
+
+// Enabled checks if a feature flag is on for the user and realm of the log context. The user is the key
+// of the percentage rollouts: a user always gets the same result for a flag while its configuration does not
+// change. Without user, a flag with a percentage is off. Unknown flags are off.
+func (f *Flags) Enabled(name string, ctxt *LogContext) bool {
+	flag, ok := f.config.Load().(FlagsConfig)[name]
+	if !ok {
+		return false
+	}
+	var user, realm string
+	if ctxt != nil {
+		user, realm = ctxt.User, ctxt.Realm
+	}
+	if override, ok := flag.Realms[realm]; ok && realm != "" {
+		flag = override
+	}
+	if !flag.Enabled {
+		return false
+	}
+	if flag.Percentage == nil || *flag.Percentage >= 100 {
+		return true
+	}
+	if user == "" || *flag.Percentage <= 0 {
+		return false
+	}
+	return float64(flagBucket(name, user)) < *flag.Percentage*100
+}
+
+// Names returns the names of the feature flags in alphabetical order.
+func (f *Flags) Names() []string {
+	flags := f.config.Load().(FlagsConfig)
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LogContext evaluates the feature flags for the log context (all of them if no name is passed) to log them.
+// For example:
+//
+//	logger.DebugC(flags.LogContext(ctxt), "Feature flags")
+func (f *Flags) LogContext(ctxt *LogContext, names ...string) *FlagsLogContext {
+	if len(names) == 0 {
+		names = f.Names()
+	}
+	evaluations := make(map[string]bool, len(names))
+	for _, name := range names {
+		evaluations[name] = f.Enabled(name, ctxt)
+	}
+	return &FlagsLogContext{Flags: evaluations}
+}
+
+// flagBucket assigns a user to a bucket (from 0 to 9999) for a flag. The flag name is part of the hash so
+// that the same users do not get every rollout first.
+func flagBucket(name, user string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(user))
+	return h.Sum32() % 10000
+}
@@ -0,0 +1,130 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func percentage(p float64) *float64 {
+	return &p
+}
+
+func TestFlagsEnabled(t *testing.T) {
+	flags := NewFlags(FlagsConfig{
+		"on":     {Enabled: true},
+		"off":    {Enabled: false},
+		"all":    {Enabled: true, Percentage: percentage(100)},
+		"none":   {Enabled: true, Percentage: percentage(0)},
+		"half":   {Enabled: true, Percentage: percentage(50)},
+		"realms": {Enabled: false, Realms: map[string]FlagConfig{"es": {Enabled: true}, "uk": {Enabled: true, Percentage: percentage(0)}}},
+	})
+	tests := []struct {
+		name     string
+		flag     string
+		ctxt     *LogContext
+		expected bool
+	}{
+		{"enabled", "on", &LogContext{User: "alice"}, true},
+		{"enabled without context", "on", nil, true},
+		{"disabled", "off", &LogContext{User: "alice"}, false},
+		{"unknown", "unknown", &LogContext{User: "alice"}, false},
+		{"100 percent", "all", &LogContext{User: "alice"}, true},
+		{"0 percent", "none", &LogContext{User: "alice"}, false},
+		{"percentage without user", "half", &LogContext{}, false},
+		{"realm override enabled", "realms", &LogContext{Realm: "es"}, true},
+		{"realm override with percentage", "realms", &LogContext{User: "alice", Realm: "uk"}, false},
+		{"realm without override", "realms", &LogContext{Realm: "fr"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := flags.Enabled(tt.flag, tt.ctxt); actual != tt.expected {
+				t.Errorf("Invalid evaluation. Actual: %t. Expected: %t", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFlagsPercentage(t *testing.T) {
+	flags := NewFlags(FlagsConfig{"rollout": {Enabled: true, Percentage: percentage(20)}})
+	enabled := 0
+	for i := 0; i < 10000; i++ {
+		ctxt := &LogContext{User: fmt.Sprintf("user%d", i)}
+		result := flags.Enabled("rollout", ctxt)
+		if result != flags.Enabled("rollout", ctxt) {
+			t.Fatalf("Evaluation must be stable for user %s", ctxt.User)
+		}
+		if result {
+			enabled++
+		}
+	}
+	if enabled < 1800 || enabled > 2200 {
+		t.Errorf("Invalid rollout. Actual: %d of 10000 users. Expected: about 2000", enabled)
+	}
+}
+
+func TestFlagsLogContext(t *testing.T) {
+	flags := NewFlags(FlagsConfig{"b": {Enabled: true}, "a": {Enabled: false}})
+	if names := flags.Names(); strings.Join(names, ",") != "a,b" {
+		t.Errorf("Invalid names. Actual: %v", names)
+	}
+	var buf bytes.Buffer
+	logger := &Logger{out: &buf, logLevel: debugLevel}
+	logger.DebugC(flags.LogContext(&LogContext{User: "alice"}), "Feature flags")
+	if !strings.Contains(buf.String(), `"flags":{"a":false,"b":true}`) {
+		t.Errorf("Invalid log. Actual: %s", buf.String())
+	}
+	data, _ := json.Marshal(flags.LogContext(nil, "b"))
+	if string(data) != `{"flags":{"b":true}}` {
+		t.Errorf("Invalid log context. Actual: %s", data)
+	}
+}
+
+func TestWatchFlags(t *testing.T) {
+	type flagsConfig struct {
+		Flags FlagsConfig `json:"flags"`
+	}
+	file := writeTestFile(t, "config.json", `{"flags": {"search": {"enabled": false}}}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	watcher, err := NewConfigWatcher(NewConfigLoader(file), &flagsConfig{})
+	if err != nil {
+		t.Fatalf("Error creating watcher. %s", err)
+	}
+	watcher.SetLogger(&Logger{out: ioutil.Discard, logLevel: infoLevel})
+	flags := WatchFlags(watcher, func(config interface{}) FlagsConfig {
+		return config.(*flagsConfig).Flags
+	})
+	if flags.Enabled("search", nil) {
+		t.Errorf("Flag must be disabled before reload")
+	}
+	writeWatchedConfig(t, file, `{"flags": {"search": {"enabled": true}}}`, time.Now().Add(time.Second))
+	if err := watcher.Reload(); err != nil {
+		t.Fatalf("Error reloading config. %s", err)
+	}
+	if !flags.Enabled("search", nil) {
+		t.Errorf("Flag must be enabled after reload")
+	}
+}
@@ -0,0 +1,447 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package gostruct builds a reflect.Type from the source code of a struct type. It enables the govice
+// commands to reflect over the configuration struct of a service without compiling it.
+//
+// Fields are rebuilt with their names, types and struct tags. Named types of the same package are resolved
+// to their underlying type (their methods are lost). As the decoding of a type with an UnmarshalJSON or
+// UnmarshalText method would change, these types are not supported: the configuration of such a struct must be
+// checked by the service itself (e.g. with govice.CheckConfig). Types of other packages are only supported if
+// they are registered in KnownTypes.
+package gostruct
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Telefonica/govice"
+)
+
+// KnownTypes are the types of other packages that can be used in a struct, indexed by
+// "<import path>.<type name>".
+var KnownTypes = map[string]reflect.Type{
+	"time.Duration":            reflect.TypeOf(time.Duration(0)),
+	"time.Time":                reflect.TypeOf(time.Time{}),
+	"net/url.URL":              reflect.TypeOf(url.URL{}),
+	"net.IP":                   reflect.TypeOf(net.IP{}),
+	"regexp.Regexp":            reflect.TypeOf(regexp.Regexp{}),
+	"encoding/json.RawMessage": reflect.TypeOf(json.RawMessage{}),
+	"github.com/Telefonica/govice.LogContext": reflect.TypeOf(govice.LogContext{}),
+	"github.com/Telefonica/govice.ByteSize":   reflect.TypeOf(govice.ByteSize(0)),
+}
+
+var builtinTypes = map[string]reflect.Type{
+	"string":      reflect.TypeOf(""),
+	"bool":        reflect.TypeOf(false),
+	"int":         reflect.TypeOf(int(0)),
+	"int8":        reflect.TypeOf(int8(0)),
+	"int16":       reflect.TypeOf(int16(0)),
+	"int32":       reflect.TypeOf(int32(0)),
+	"rune":        reflect.TypeOf(rune(0)),
+	"int64":       reflect.TypeOf(int64(0)),
+	"uint":        reflect.TypeOf(uint(0)),
+	"uint8":       reflect.TypeOf(uint8(0)),
+	"byte":        reflect.TypeOf(byte(0)),
+	"uint16":      reflect.TypeOf(uint16(0)),
+	"uint32":      reflect.TypeOf(uint32(0)),
+	"uint64":      reflect.TypeOf(uint64(0)),
+	"float32":     reflect.TypeOf(float32(0)),
+	"float64":     reflect.TypeOf(float64(0)),
+	"interface{}": reflect.TypeOf((*interface{})(nil)).Elem(),
+	"any":         reflect.TypeOf((*interface{})(nil)).Elem(),
+}
+
+// loader resolves the types of a package.
+type loader struct {
+	specs    map[string]*ast.TypeSpec
+	imports  map[*ast.TypeSpec]map[string]string
+	resolved map[string]reflect.Type
+	loading  map[string]bool
+	// decoders are the unmarshal methods of the types, indexed by the type name
+	decoders map[string]string
+}
+
+// decoderMethods are the methods that change how a type is decoded from JSON.
+var decoderMethods = map[string]bool{
+	"UnmarshalJSON": true,
+	"UnmarshalText": true,
+}
+
+// Load parses the Go files of the package in dir (excluding tests) and returns the reflect.Type of the
+// struct typeName.
+func Load(dir, typeName string) (reflect.Type, error) {
+	fset := token.NewFileSet()
+	notTest := func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}
+	pkgs, err := parser.ParseDir(fset, dir, notTest, 0)
+	if err != nil {
+		return nil, err
+	}
+	l := &loader{
+		specs:    map[string]*ast.TypeSpec{},
+		imports:  map[*ast.TypeSpec]map[string]string{},
+		resolved: map[string]reflect.Type{},
+		loading:  map[string]bool{},
+		decoders: map[string]string{},
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			imports := fileImports(file)
+			for _, decl := range file.Decls {
+				if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+					if funcDecl.Recv != nil && decoderMethods[funcDecl.Name.Name] {
+						l.decoders[receiverName(funcDecl.Recv.List[0].Type)] = funcDecl.Name.Name
+					}
+					continue
+				}
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range genDecl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					l.specs[typeSpec.Name.Name] = typeSpec
+					l.imports[typeSpec] = imports
+				}
+			}
+		}
+	}
+	spec, ok := l.specs[typeName]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in %s", typeName, dir)
+	}
+	if _, ok := spec.Type.(*ast.StructType); !ok {
+		return nil, fmt.Errorf("type %s is not a struct", typeName)
+	}
+	return l.named(typeName)
+}
+
+// fileImports returns the import paths of a file indexed by the package name used in the file.
+func fileImports(file *ast.File) map[string]string {
+	imports := map[string]string{}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// receiverName returns the type name of a method receiver (e.g. endpoint for *endpoint).
+func receiverName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	}
+	return ""
+}
+
+func (l *loader) named(name string) (reflect.Type, error) {
+	if t, ok := l.resolved[name]; ok {
+		return t, nil
+	}
+	spec, ok := l.specs[name]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", name)
+	}
+	if method, ok := l.decoders[name]; ok {
+		return nil, fmt.Errorf("type %s has a %s method, which is lost when the type is rebuilt", name, method)
+	}
+	if l.loading[name] {
+		// Recursive types are replaced by an empty interface
+		return builtinTypes["interface{}"], nil
+	}
+	l.loading[name] = true
+	defer delete(l.loading, name)
+	t, err := l.typeOf(spec.Type, l.imports[spec])
+	if err != nil {
+		return nil, fmt.Errorf("type %s: %s", name, err)
+	}
+	l.resolved[name] = t
+	return t, nil
+}
+
+func (l *loader) typeOf(expr ast.Expr, imports map[string]string) (reflect.Type, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if t, ok := builtinTypes[e.Name]; ok {
+			return t, nil
+		}
+		return l.named(e.Name)
+	case *ast.StarExpr:
+		t, err := l.typeOf(e.X, imports)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PtrTo(t), nil
+	case *ast.ArrayType:
+		elem, err := l.typeOf(e.Elt, imports)
+		if err != nil {
+			return nil, err
+		}
+		if e.Len == nil {
+			return reflect.SliceOf(elem), nil
+		}
+		lit, ok := e.Len.(*ast.BasicLit)
+		if !ok {
+			return nil, fmt.Errorf("unsupported array length")
+		}
+		n, err := strconv.Atoi(lit.Value)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ArrayOf(n, elem), nil
+	case *ast.MapType:
+		key, err := l.typeOf(e.Key, imports)
+		if err != nil {
+			return nil, err
+		}
+		value, err := l.typeOf(e.Value, imports)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(key, value), nil
+	case *ast.InterfaceType:
+		return builtinTypes["interface{}"], nil
+	case *ast.SelectorExpr:
+		pkg, ok := e.X.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("unsupported type expression")
+		}
+		name := imports[pkg.Name] + "." + e.Sel.Name
+		if t, ok := KnownTypes[name]; ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("unsupported type %s", name)
+	case *ast.StructType:
+		return l.structOf(e, imports)
+	}
+	return nil, fmt.Errorf("unsupported type expression %T", expr)
+}
+
+// structField is a field of a struct with the depth of the embedded struct that declares it (0 for the fields
+// of the struct itself).
+type structField struct {
+	reflect.StructField
+	depth int
+}
+
+// structOf builds a struct type with the exported fields. Fields of embedded structs are inlined, skipping the
+// fields hidden by the same rules as encoding/json and Go (the shallowest field wins).
+func (l *loader) structOf(s *ast.StructType, imports map[string]string) (reflect.Type, error) {
+	fields, err := l.structFields(s, imports)
+	if err != nil {
+		return nil, err
+	}
+	fields, err = dominantFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	structFields := make([]reflect.StructField, len(fields))
+	for i, field := range fields {
+		structFields[i] = field.StructField
+	}
+	return reflect.StructOf(structFields), nil
+}
+
+// structFields returns the exported fields of a struct, including the fields of the embedded structs
+// (with their depth).
+func (l *loader) structFields(s *ast.StructType, imports map[string]string) ([]structField, error) {
+	var fields []structField
+	for _, field := range s.Fields.List {
+		if len(field.Names) > 0 && !hasExportedName(field.Names) {
+			// Skip unexported fields (their types might not be supported)
+			continue
+		}
+		t, err := l.typeOf(field.Type, imports)
+		if err != nil {
+			return nil, err
+		}
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			value, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(value)
+		}
+		if len(field.Names) == 0 {
+			// Embedded field
+			jsonName := strings.Split(tag.Get("json"), ",")[0]
+			if t.Kind() == reflect.Struct && jsonName == "" {
+				embedded, err := l.embeddedFields(field.Type, t)
+				if err != nil {
+					return nil, err
+				}
+				for _, f := range embedded {
+					f.depth++
+					fields = append(fields, f)
+				}
+				continue
+			}
+			name := embeddedName(field.Type)
+			if !ast.IsExported(name) {
+				continue
+			}
+			fields = append(fields, structField{StructField: reflect.StructField{Name: name, Type: t, Tag: tag}})
+			continue
+		}
+		for _, name := range field.Names {
+			if ast.IsExported(name.Name) {
+				fields = append(fields, structField{StructField: reflect.StructField{Name: name.Name, Type: t, Tag: tag}})
+			}
+		}
+	}
+	return fields, nil
+}
+
+// embeddedFields returns the fields of an embedded struct of type t. The depth of the fields is only known for
+// the structs declared in the package; the fields of other structs are at depth 0.
+func (l *loader) embeddedFields(expr ast.Expr, t reflect.Type) ([]structField, error) {
+	if ident, ok := expr.(*ast.Ident); ok && !l.loading[ident.Name] {
+		if spec, ok := l.specs[ident.Name]; ok {
+			if s, ok := spec.Type.(*ast.StructType); ok {
+				l.loading[ident.Name] = true
+				defer delete(l.loading, ident.Name)
+				return l.structFields(s, l.imports[spec])
+			}
+		}
+	}
+	fields := make([]structField, t.NumField())
+	for i := range fields {
+		fields[i] = structField{StructField: t.Field(i)}
+	}
+	return fields, nil
+}
+
+// dominantFields removes the fields hidden by other fields. Fields with the same JSON name follow the rules of
+// encoding/json: the shallowest field wins and, at the same depth, the only one with a json tag. Then, fields with
+// the same Go name follow the rules of Go: the shallowest field wins, and an ambiguous name is an error (because
+// the struct type cannot be built).
+func dominantFields(fields []structField) ([]structField, error) {
+	hidden := make([]bool, len(fields))
+	hide := func(key func(f structField) (string, bool), tagged func(f structField) bool, ambiguous func(name string) error) error {
+		groups := map[string][]int{}
+		for i, f := range fields {
+			if name, ok := key(f); ok && !hidden[i] {
+				groups[name] = append(groups[name], i)
+			}
+		}
+		for name, group := range groups {
+			minDepth := fields[group[0]].depth
+			for _, i := range group {
+				if fields[i].depth < minDepth {
+					minDepth = fields[i].depth
+				}
+			}
+			var dominant []int
+			for _, i := range group {
+				if fields[i].depth == minDepth {
+					dominant = append(dominant, i)
+				}
+			}
+			if len(dominant) > 1 {
+				var taggedFields []int
+				for _, i := range dominant {
+					if tagged(fields[i]) {
+						taggedFields = append(taggedFields, i)
+					}
+				}
+				if len(taggedFields) != 1 {
+					if err := ambiguous(name); err != nil {
+						return err
+					}
+					taggedFields = nil
+				}
+				dominant = taggedFields
+			}
+			for _, i := range group {
+				hidden[i] = len(dominant) == 0 || i != dominant[0]
+			}
+		}
+		return nil
+	}
+	jsonName := func(f structField) (string, bool) {
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			return "", false
+		}
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name, true
+		}
+		return f.Name, true
+	}
+	hasJSONName := func(f structField) bool {
+		return strings.Split(f.Tag.Get("json"), ",")[0] != ""
+	}
+	goName := func(f structField) (string, bool) {
+		return f.Name, true
+	}
+	if err := hide(jsonName, hasJSONName, func(string) error { return nil }); err != nil {
+		return nil, err
+	}
+	notTagged := func(structField) bool { return false }
+	if err := hide(goName, notTagged, func(name string) error { return fmt.Errorf("ambiguous field %s", name) }); err != nil {
+		return nil, err
+	}
+	var dominant []structField
+	for i, f := range fields {
+		if !hidden[i] {
+			dominant = append(dominant, f)
+		}
+	}
+	return dominant, nil
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+func hasExportedName(names []*ast.Ident) bool {
+	for _, name := range names {
+		if name.IsExported() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gostruct
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testSource = `package main
+
+import (
+	"sync"
+	"time"
+)
+
+type level string
+
+type base struct {
+	Address string ` + "`json:\"address\"`" + `
+}
+
+type node struct {
+	Children []node
+}
+
+type config struct {
+	base
+	LogLevel level             ` + "`json:\"logLevel\" enum:\"DEBUG,INFO\"`" + `
+	Timeout  time.Duration     ` + "`json:\"timeout\"`" + `
+	Ports    []int             ` + "`json:\"ports\"`" + `
+	Headers  map[string]string ` + "`json:\"headers\"`" + `
+	Tree     *node             ` + "`json:\"tree\"`" + `
+	mutex    sync.Mutex
+}
+
+type unsupported struct {
+	Mutex sync.Mutex
+}
+
+type inner struct {
+	Name string
+	Port int ` + "`json:\"port\"`" + `
+}
+
+type shadowed struct {
+	Name  string
+	inner
+	Extra any
+}
+
+type left struct {
+	Name string ` + "`json:\"left\"`" + `
+}
+
+type right struct {
+	Name string ` + "`json:\"right\"`" + `
+}
+
+type ambiguous struct {
+	left
+	right
+}
+
+type endpoint struct {
+	Host string
+	Port int
+}
+
+func (e *endpoint) UnmarshalText(text []byte) error {
+	return nil
+}
+
+type decoded struct {
+	DB endpoint ` + "`json:\"db\"`" + `
+}
+`
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gostruct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(testSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	typ, err := Load(dir, "config")
+	if err != nil {
+		t.Fatalf("Error loading type. %s", err)
+	}
+	expected := []reflect.StructField{
+		{Name: "Address", Type: reflect.TypeOf(""), Tag: `json:"address"`},
+		{Name: "LogLevel", Type: reflect.TypeOf(""), Tag: `json:"logLevel" enum:"DEBUG,INFO"`},
+		{Name: "Timeout", Type: reflect.TypeOf(time.Duration(0)), Tag: `json:"timeout"`},
+		{Name: "Ports", Type: reflect.TypeOf([]int{}), Tag: `json:"ports"`},
+		{Name: "Headers", Type: reflect.TypeOf(map[string]string{}), Tag: `json:"headers"`},
+	}
+	if typ.NumField() != len(expected)+1 {
+		t.Fatalf("Invalid number of fields. Actual: %d", typ.NumField())
+	}
+	for i, field := range expected {
+		actual := typ.Field(i)
+		if actual.Name != field.Name || actual.Type != field.Type || actual.Tag != field.Tag {
+			t.Errorf("Invalid field %d. Actual: %+v. Expected: %+v", i, actual, field)
+		}
+	}
+	// Recursive type is replaced by interface{} in the cycle
+	tree := typ.Field(len(expected)).Type
+	if tree.Kind() != reflect.Ptr || tree.Elem().Field(0).Type.Elem().Kind() != reflect.Interface {
+		t.Errorf("Invalid recursive type. Actual: %s", tree)
+	}
+
+	// Fields of embedded structs are hidden by the shallower fields
+	typ, err = Load(dir, "shadowed")
+	if err != nil {
+		t.Fatalf("Error loading type with shadowed fields. %s", err)
+	}
+	expected = []reflect.StructField{
+		{Name: "Name", Type: reflect.TypeOf("")},
+		{Name: "Port", Type: reflect.TypeOf(0), Tag: `json:"port"`},
+		{Name: "Extra", Type: reflect.TypeOf((*interface{})(nil)).Elem()},
+	}
+	if typ.NumField() != len(expected) {
+		t.Fatalf("Invalid number of shadowed fields. Actual: %d", typ.NumField())
+	}
+	for i, field := range expected {
+		actual := typ.Field(i)
+		if actual.Name != field.Name || actual.Type != field.Type || actual.Tag != field.Tag {
+			t.Errorf("Invalid field %d. Actual: %+v. Expected: %+v", i, actual, field)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		typeName string
+	}{
+		{"not found", "missing"},
+		{"ambiguous field", "ambiguous"},
+		{"unmarshal method", "decoded"},
+		{"not a struct", "level"},
+		{"unsupported type", "unsupported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(dir, tt.typeName); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
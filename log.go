@@ -49,6 +49,8 @@ const (
 
 var defaultLogLevel = infoLevel
 
+var defaultLogWriter io.Writer = os.Stdout
+
 func levelByName(levelName string) level {
 	levelName = strings.ToUpper(levelName)
 	for i, name := range LogLevelNames {
@@ -60,18 +62,23 @@ func levelByName(levelName string) level {
 }
 
 // Logger type.
+// The child loggers (see With) share the writer, level, encoder and mutex of their root logger.
 type Logger struct {
 	out      io.Writer
 	logLevel level
 	context  interface{}
+	encoder  Encoder
 	mutex    sync.Mutex
+	root     *Logger
+	fields   []LogField
 }
 
 // NewLogger to create a Logger.
 func NewLogger() *Logger {
 	return &Logger{
-		out:      os.Stdout,
+		out:      defaultLogWriter,
 		logLevel: defaultLogLevel,
+		encoder:  defaultEncoder,
 	}
 }
 
@@ -80,6 +87,13 @@ func SetDefaultLogLevel(level string) {
 	defaultLogLevel = levelByName(level)
 }
 
+// SetDefaultLogWriter sets the default log writer (os.Stdout if not set) of the new loggers, including the loggers
+// created for every request by the middlewares. This default can be overridden with SetWriter method.
+// Note that the writer is shared by several loggers, so it must be safe for concurrent use (e.g. RotatingFile).
+func SetDefaultLogWriter(w io.Writer) {
+	defaultLogWriter = w
+}
+
 // SetLogContext to set a global context.
 func (l *Logger) SetLogContext(context interface{}) {
 	l.context = context
@@ -92,41 +106,108 @@ func (l *Logger) GetLogContext() interface{} {
 
 // SetLevel to set the log level.
 func (l *Logger) SetLevel(levelName string) {
-	l.logLevel = levelByName(levelName)
+	l.shared().logLevel = levelByName(levelName)
 }
 
 // GetLevel to return the log level.
 func (l *Logger) GetLevel() string {
-	return LogLevelNames[l.logLevel]
+	return LogLevelNames[l.shared().logLevel]
 }
 
 // SetWriter to set the log writer
 func (l *Logger) SetWriter(o io.Writer) {
-	l.out = o
+	l.shared().out = o
 }
 
 // GetWriter to get the log writer
 func (l *Logger) GetWriter() io.Writer {
-	return l.out
+	return l.shared().out
+}
+
+// SetFormat to set the log format (see LogFormatNames). An unknown format selects the JSON format.
+func (l *Logger) SetFormat(format string) {
+	l.shared().encoder = encoderByName(format)
+}
+
+// SetEncoder to set the encoder of the log records (e.g. a custom format).
+func (l *Logger) SetEncoder(encoder Encoder) {
+	l.shared().encoder = encoder
+}
+
+// GetEncoder to get the encoder of the log records.
+func (l *Logger) GetEncoder() Encoder {
+	if encoder := l.shared().encoder; encoder != nil {
+		return encoder
+	}
+	return JSONEncoder{}
 }
 
-func (l *Logger) log(logLevel level, context interface{}, message string, args ...interface{}) {
-	if logLevel < l.logLevel {
+// shared returns the logger that owns the writer, level, encoder and mutex (the root logger of a child logger).
+func (l *Logger) shared() *Logger {
+	if l.root != nil {
+		return l.root
+	}
+	return l
+}
+
+// With returns a child logger that adds the key/value pairs (e.g. "orderId", id, "attempt", 2) to every log record.
+// Keys must be strings (a value without key, or with a key of other type, is logged with the key !BADKEY).
+// The child logger inherits the log context and the fields of l, and it shares the writer, level and encoder
+// with l (e.g. changing the level of the child logger also changes the level of l).
+func (l *Logger) With(keyValues ...interface{}) *Logger {
+	fields := make([]LogField, 0, len(l.fields)+len(keyValues)/2)
+	fields = append(fields, l.fields...)
+	return &Logger{
+		context: l.context,
+		root:    l.shared(),
+		fields:  appendLogFields(fields, keyValues),
+	}
+}
+
+func (l *Logger) log(logLevel level, context interface{}, keyValues []interface{}, message string, args ...interface{}) {
+	shared := l.shared()
+	if logLevel < shared.logLevel {
 		return
 	}
 	text := message
 	if len(args) > 0 {
 		text = fmt.Sprintf(message, args...)
 	}
+	fields := l.fields
+	if len(keyValues) > 0 {
+		fields = appendLogFields(append([]LogField{}, l.fields...), keyValues)
+	}
+	l.write(time.Now(), logLevel, context, fields, text)
+}
+
+// write encodes a log record and writes it. Note that the log level is not checked.
+func (l *Logger) write(t time.Time, logLevel level, context interface{}, fields []LogField, message string) {
+	shared := l.shared()
 	var buf bytes.Buffer
-	writeDoc(&buf, time.Now(), LogLevelNames[logLevel], l.context, context, text)
+	shared.GetEncoder().Encode(&buf, &LogEntry{
+		Time:          t,
+		Level:         LogLevelNames[logLevel],
+		Context:       l.context,
+		CustomContext: context,
+		KeyValues:     fields,
+		Message:       message,
+	})
 	bytes := buf.Bytes()
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
-	l.out.Write(bytes)
+	shared.mutex.Lock()
+	defer shared.mutex.Unlock()
+	if out, ok := shared.out.(levelWriter); ok {
+		out.writeLevel(logLevel, bytes)
+		return
+	}
+	shared.out.Write(bytes)
 }
 
-func writeDoc(buf *bytes.Buffer, time time.Time, level string, context, customContext interface{}, message string) {
+// levelWriter is a writer that also receives the level of the log records (e.g. AsyncWriter).
+type levelWriter interface {
+	writeLevel(logLevel level, p []byte) (int, error)
+}
+
+func writeDoc(buf *bytes.Buffer, time time.Time, level string, context, customContext interface{}, fields []LogField, message string) {
 	buf.WriteByte('{')
 	writeField(buf, "time", time.Format(RFC3339Milli))
 	buf.WriteByte(',')
@@ -138,6 +219,10 @@ func writeDoc(buf *bytes.Buffer, time time.Time, level string, context, customCo
 	if length := writeObject(buf, customContext); length > 0 {
 		buf.WriteByte(',')
 	}
+	for _, field := range fields {
+		writeRawField(buf, field.Key, field.Value)
+		buf.WriteByte(',')
+	}
 	writeField(buf, "msg", message)
 	buf.WriteByte('}')
 	buf.WriteByte('\n')
@@ -153,11 +238,19 @@ func writeField(buf *bytes.Buffer, key string, value interface{}) {
 	}
 }
 
+func writeRawField(buf *bytes.Buffer, key string, value json.RawMessage) {
+	if jsonKey, err := json.Marshal(key); err == nil {
+		buf.Write(jsonKey)
+	}
+	buf.WriteByte(':')
+	buf.Write(value)
+}
+
 func writeObject(buf *bytes.Buffer, v interface{}) int {
 	if v == nil {
 		return 0
 	}
-	if b, err := json.Marshal(v); err == nil {
+	if b, err := MarshalRedacted(v); err == nil {
 		length := len(b)
 		if length > 2 && b[0] == '{' && b[length-1] == '}' {
 			if _, err := buf.Write(b[1 : length-1]); err == nil {
@@ -170,52 +263,77 @@ func writeObject(buf *bytes.Buffer, v interface{}) int {
 
 // Debug to log a message at debug level
 func (l *Logger) Debug(message string, args ...interface{}) {
-	l.log(debugLevel, nil, message, args...)
+	l.log(debugLevel, nil, nil, message, args...)
 }
 
 // DebugC to log a message at debug level with custom context
 func (l *Logger) DebugC(context interface{}, message string, args ...interface{}) {
-	l.log(debugLevel, context, message, args...)
+	l.log(debugLevel, context, nil, message, args...)
+}
+
+// DebugKV to log a message at debug level with key/value pairs (e.g. "orderId", id)
+func (l *Logger) DebugKV(message string, keyValues ...interface{}) {
+	l.log(debugLevel, nil, keyValues, message)
 }
 
 // Info to log a message at info level
 func (l *Logger) Info(message string, args ...interface{}) {
-	l.log(infoLevel, nil, message, args...)
+	l.log(infoLevel, nil, nil, message, args...)
 }
 
 // InfoC to log a message at info level
 func (l *Logger) InfoC(context interface{}, message string, args ...interface{}) {
-	l.log(infoLevel, context, message, args...)
+	l.log(infoLevel, context, nil, message, args...)
+}
+
+// InfoKV to log a message at info level with key/value pairs (e.g. "orderId", id)
+func (l *Logger) InfoKV(message string, keyValues ...interface{}) {
+	l.log(infoLevel, nil, keyValues, message)
 }
 
 // Warn to log a message at warn level
 func (l *Logger) Warn(message string, args ...interface{}) {
-	l.log(warnLevel, nil, message, args...)
+	l.log(warnLevel, nil, nil, message, args...)
 }
 
 // WarnC to log a message at warn level
 func (l *Logger) WarnC(context interface{}, message string, args ...interface{}) {
-	l.log(warnLevel, context, message, args...)
+	l.log(warnLevel, context, nil, message, args...)
+}
+
+// WarnKV to log a message at warn level with key/value pairs (e.g. "orderId", id)
+func (l *Logger) WarnKV(message string, keyValues ...interface{}) {
+	l.log(warnLevel, nil, keyValues, message)
 }
 
 // Error to log a message at error level
 func (l *Logger) Error(message string, args ...interface{}) {
-	l.log(errorLevel, nil, message, args...)
+	l.log(errorLevel, nil, nil, message, args...)
 }
 
 // ErrorC to log a message at error level
 func (l *Logger) ErrorC(context interface{}, message string, args ...interface{}) {
-	l.log(errorLevel, context, message, args...)
+	l.log(errorLevel, context, nil, message, args...)
+}
+
+// ErrorKV to log a message at error level with key/value pairs (e.g. "orderId", id)
+func (l *Logger) ErrorKV(message string, keyValues ...interface{}) {
+	l.log(errorLevel, nil, keyValues, message)
 }
 
 // Fatal to log a message at fatal level
 func (l *Logger) Fatal(message string, args ...interface{}) {
-	l.log(fatalLevel, nil, message, args...)
+	l.log(fatalLevel, nil, nil, message, args...)
 }
 
 // FatalC to log a message at fatal level
 func (l *Logger) FatalC(context interface{}, message string, args ...interface{}) {
-	l.log(fatalLevel, context, message, args...)
+	l.log(fatalLevel, context, nil, message, args...)
+}
+
+// FatalKV to log a message at fatal level with key/value pairs (e.g. "orderId", id)
+func (l *Logger) FatalKV(message string, keyValues ...interface{}) {
+	l.log(fatalLevel, nil, keyValues, message)
 }
 
 // DebugResponse to dump the response at debug level.
@@ -225,7 +343,7 @@ func (l *Logger) DebugResponse(message string, r *http.Response) {
 
 // DebugResponseC to dump the response at debug level.
 func (l *Logger) DebugResponseC(context interface{}, message string, r *http.Response) {
-	if r != nil && l.logLevel <= debugLevel {
+	if r != nil && l.shared().logLevel <= debugLevel {
 		if dump, err := httputil.DumpResponse(r, true); err == nil {
 			l.DebugC(context, "%s. %s", message, dump)
 		}
@@ -239,7 +357,7 @@ func (l *Logger) DebugRequest(message string, r *http.Request) {
 
 // DebugRequestC to dump the request at debug level.
 func (l *Logger) DebugRequestC(context interface{}, message string, r *http.Request) {
-	if r != nil && l.logLevel <= debugLevel {
+	if r != nil && l.shared().logLevel <= debugLevel {
 		if dump, err := httputil.DumpRequest(r, true); err == nil {
 			l.DebugC(context, "%s. %s", message, dump)
 		}
@@ -253,7 +371,7 @@ func (l *Logger) DebugRequestOut(message string, r *http.Request) {
 
 // DebugRequestOutC to dump the output request at debug level.
 func (l *Logger) DebugRequestOutC(context interface{}, message string, r *http.Request) {
-	if r != nil && l.logLevel <= debugLevel {
+	if r != nil && l.shared().logLevel <= debugLevel {
 		if dump, err := httputil.DumpRequestOut(r, true); err == nil {
 			l.DebugC(context, "%s. %s", message, dump)
 		}
@@ -0,0 +1,252 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy selects what AsyncWriter does with a log record when its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until there is room in the queue (no log record is lost).
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the new log record.
+	OverflowDropNewest
+	// OverflowDropDebug drops the DEBUG records first: a new record of other level replaces the oldest DEBUG record
+	// of the queue. If there is no DEBUG record in the queue, the new record is dropped.
+	OverflowDropDebug
+)
+
+// DroppedLogsMessage is the message of the log record that reports the records dropped by an AsyncWriter.
+var DroppedLogsMessage = "Log records dropped"
+
+// asyncRecord is a log record queued by AsyncWriter. Records written with Write have no level.
+type asyncRecord struct {
+	logLevel level
+	hasLevel bool
+	data     []byte
+}
+
+// AsyncWriter is an io.Writer that queues the log records and writes them in background to another writer, so a
+// slow writer (e.g. a pipe) does not stall the goroutines logging. The queue is bounded (besides the record being
+// written) and the OverflowPolicy selects what to do when it is full. Call Close (or Flush) before finishing the process to write the queued
+// records.
+type AsyncWriter struct {
+	out       io.Writer
+	size      int
+	policy    OverflowPolicy
+	mutex     sync.Mutex
+	syncMutex sync.Mutex
+	notEmpty  *sync.Cond
+	notFull   *sync.Cond
+	idle      *sync.Cond
+	queue     []asyncRecord
+	writing   bool
+	closed    bool
+	done      chan struct{}
+	dropped   uint64
+	reported  uint64
+	stop      chan struct{}
+	reporter  sync.WaitGroup
+	logger    *Logger
+}
+
+// NewAsyncWriter creates an AsyncWriter that writes to out with a queue of size log records.
+func NewAsyncWriter(out io.Writer, size int, policy OverflowPolicy) *AsyncWriter {
+	if size < 1 {
+		size = 1
+	}
+	a := &AsyncWriter{out: out, size: size, policy: policy, done: make(chan struct{})}
+	a.notEmpty = sync.NewCond(&a.mutex)
+	a.notFull = sync.NewCond(&a.mutex)
+	a.idle = sync.NewCond(&a.mutex)
+	go a.run()
+	return a
+}
+
+// Write queues a log record without level (see OverflowDropDebug).
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	return a.enqueue(asyncRecord{data: p})
+}
+
+func (a *AsyncWriter) writeLevel(logLevel level, p []byte) (int, error) {
+	return a.enqueue(asyncRecord{logLevel: logLevel, hasLevel: true, data: p})
+}
+
+// enqueue queues a log record applying the overflow policy. After closing the writer, the record is written
+// synchronously.
+func (a *AsyncWriter) enqueue(record asyncRecord) (int, error) {
+	a.mutex.Lock()
+	for !a.closed && len(a.queue) >= a.size && a.policy == OverflowBlock {
+		a.notFull.Wait()
+	}
+	if a.closed {
+		a.mutex.Unlock()
+		// Wait until the queued records are written
+		<-a.done
+		a.syncMutex.Lock()
+		defer a.syncMutex.Unlock()
+		return a.out.Write(record.data)
+	}
+	defer a.mutex.Unlock()
+	if len(a.queue) >= a.size {
+		i := -1
+		if a.policy == OverflowDropDebug && !record.isDebug() {
+			i = a.indexOfDebug()
+		}
+		a.dropped++
+		if i < 0 {
+			return len(record.data), nil
+		}
+		a.queue = append(a.queue[:i], a.queue[i+1:]...)
+	}
+	record.data = append([]byte{}, record.data...)
+	a.queue = append(a.queue, record)
+	a.notEmpty.Signal()
+	return len(record.data), nil
+}
+
+func (r asyncRecord) isDebug() bool {
+	return r.hasLevel && r.logLevel == debugLevel
+}
+
+// indexOfDebug returns the index of the oldest DEBUG record of the queue (or -1).
+func (a *AsyncWriter) indexOfDebug() int {
+	for i, record := range a.queue {
+		if record.isDebug() {
+			return i
+		}
+	}
+	return -1
+}
+
+// run writes the queued records, one at a time, until the writer is closed. Taking a single record keeps the
+// memory bounded to the size of the queue plus the record being written.
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+	for {
+		a.mutex.Lock()
+		for len(a.queue) == 0 && !a.closed {
+			a.notEmpty.Wait()
+		}
+		if len(a.queue) == 0 {
+			a.mutex.Unlock()
+			return
+		}
+		record := a.queue[0]
+		a.queue[0] = asyncRecord{}
+		a.queue = a.queue[1:]
+		a.writing = true
+		a.notFull.Signal()
+		a.mutex.Unlock()
+
+		a.out.Write(record.data)
+
+		a.mutex.Lock()
+		a.writing = false
+		a.idle.Broadcast()
+		a.mutex.Unlock()
+	}
+}
+
+// Flush waits until every queued log record is written.
+func (a *AsyncWriter) Flush() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for len(a.queue) > 0 || a.writing {
+		a.idle.Wait()
+	}
+}
+
+// Dropped returns the number of log records dropped by the overflow policy.
+func (a *AsyncWriter) Dropped() uint64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.dropped
+}
+
+// ReportDropped logs a warning with logger every interval if some log records were dropped since the last report
+// (with the number of dropped records in the field droppedLogs). The last report is done when the writer is
+// closed; with a non-positive interval, the dropped records are only reported when the writer is closed. Note that a report is also a log record that may be dropped (and reported later) if logger writes to
+// this AsyncWriter.
+func (a *AsyncWriter) ReportDropped(logger *Logger, interval time.Duration) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.stop != nil || a.closed {
+		return
+	}
+	stop := make(chan struct{})
+	a.stop = stop
+	a.logger = logger
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	a.reporter.Add(1)
+	go func() {
+		defer a.reporter.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				a.reportDropped(logger)
+			}
+		}
+	}()
+}
+
+func (a *AsyncWriter) reportDropped(logger *Logger) {
+	a.mutex.Lock()
+	dropped := a.dropped - a.reported
+	a.reported = a.dropped
+	a.mutex.Unlock()
+	if dropped > 0 {
+		logger.WarnKV(DroppedLogsMessage, "droppedLogs", dropped)
+	}
+}
+
+// Close writes the queued log records, stops the background writing and reports the dropped records
+// (see ReportDropped). The log records written afterwards are written synchronously. Note that the underlying
+// writer is not closed.
+func (a *AsyncWriter) Close() error {
+	a.mutex.Lock()
+	stop := a.stop
+	a.stop = nil
+	a.mutex.Unlock()
+	if stop != nil {
+		close(stop)
+		a.reporter.Wait()
+	}
+	a.mutex.Lock()
+	a.closed = true
+	a.notEmpty.Broadcast()
+	a.notFull.Broadcast()
+	logger := a.logger
+	a.mutex.Unlock()
+	<-a.done
+	if logger != nil {
+		a.reportDropped(logger)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateWriter is a slow writer that blocks the writes until the gate is opened.
+type gateWriter struct {
+	mutex   sync.Mutex
+	buf     bytes.Buffer
+	once    sync.Once
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{entered: make(chan struct{}), gate: make(chan struct{})}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.entered) })
+	<-w.gate
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) String() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriterDropNewest(t *testing.T) {
+	out := newGateWriter()
+	a := NewAsyncWriter(out, 2, OverflowDropNewest)
+	a.Write([]byte("1\n"))
+	<-out.entered
+	for _, record := range []string{"2\n", "3\n", "4\n"} {
+		if n, err := a.Write([]byte(record)); n != len(record) || err != nil {
+			t.Errorf("Invalid write. Actual: %d, %v", n, err)
+		}
+	}
+	close(out.gate)
+	a.Flush()
+	if actual := out.String(); actual != "1\n2\n3\n" {
+		t.Errorf("Invalid output. Actual: %q", actual)
+	}
+	if dropped := a.Dropped(); dropped != 1 {
+		t.Errorf("Invalid dropped records. Actual: %d. Expected: 1", dropped)
+	}
+	a.Close()
+	a.Write([]byte("5\n"))
+	if actual := out.String(); actual != "1\n2\n3\n5\n" {
+		t.Errorf("Invalid output after closing. Actual: %q", actual)
+	}
+}
+
+func TestAsyncWriterDropDebug(t *testing.T) {
+	out := newGateWriter()
+	a := NewAsyncWriter(out, 2, OverflowDropDebug)
+	logger := &Logger{out: a, logLevel: debugLevel, encoder: ConsoleEncoder{NoColor: true}}
+	logger.Info("first")
+	<-out.entered
+	logger.Debug("debug1")
+	logger.Info("info1")
+	logger.Info("info2")
+	logger.Debug("debug2")
+	logger.Error("error1")
+	close(out.gate)
+	a.Close()
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		messages = append(messages, strings.TrimSpace(line[13:]))
+	}
+	expected := "INFO  first,INFO  info1,INFO  info2"
+	if actual := strings.Join(messages, ","); actual != expected {
+		t.Errorf("Invalid output. Actual: %s. Expected: %s", actual, expected)
+	}
+	if dropped := a.Dropped(); dropped != 3 {
+		t.Errorf("Invalid dropped records. Actual: %d. Expected: 3", dropped)
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	out := newGateWriter()
+	a := NewAsyncWriter(out, 1, OverflowBlock)
+	a.Write([]byte("1\n"))
+	<-out.entered
+	a.Write([]byte("2\n"))
+	written := make(chan struct{})
+	go func() {
+		a.Write([]byte("3\n"))
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Errorf("Expected write blocked with a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(out.gate)
+	<-written
+	a.Close()
+	if actual := out.String(); actual != "1\n2\n3\n" {
+		t.Errorf("Invalid output. Actual: %q", actual)
+	}
+	if dropped := a.Dropped(); dropped != 0 {
+		t.Errorf("Invalid dropped records. Actual: %d. Expected: 0", dropped)
+	}
+}
+
+func TestAsyncWriterReportDropped(t *testing.T) {
+	out := newGateWriter()
+	a := NewAsyncWriter(out, 1, OverflowDropNewest)
+	logger := &Logger{out: a, logLevel: infoLevel}
+	a.ReportDropped(logger, time.Hour)
+	logger.Info("first")
+	<-out.entered
+	logger.Info("second")
+	logger.Info("dropped")
+	close(out.gate)
+	a.Close()
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Invalid number of log records. Actual: %d. Logs: %s", len(lines), out.String())
+	}
+	expected := `"lvl":"WARN","droppedLogs":1,"msg":"Log records dropped"}`
+	if !strings.HasSuffix(lines[2], expected) {
+		t.Errorf("Invalid report. Actual: %s. Expected suffix: %s", lines[2], expected)
+	}
+}
+
+func TestAsyncWriterFlush(t *testing.T) {
+	out := newGateWriter()
+	a := NewAsyncWriter(out, 10, OverflowBlock)
+	defer a.Close()
+	a.Write([]byte("1\n"))
+	<-out.entered
+	a.Write([]byte("2\n"))
+	a.Write([]byte("3\n"))
+	flushed := make(chan struct{})
+	go func() {
+		a.Flush()
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+		t.Errorf("Expected flush blocked by the slow writer")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(out.gate)
+	select {
+	case <-flushed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Flush not finished after writing the records")
+	}
+	if actual := out.String(); actual != "1\n2\n3\n" {
+		t.Errorf("Invalid output after flush. Actual: %q", actual)
+	}
+}
+
+func TestAsyncWriterReportDroppedOnClose(t *testing.T) {
+	out := newGateWriter()
+	a := NewAsyncWriter(out, 1, OverflowDropNewest)
+	logger := &Logger{out: a, logLevel: infoLevel}
+	// A non-positive interval only reports on close
+	a.ReportDropped(logger, 0)
+	logger.Info("first")
+	<-out.entered
+	logger.Info("second")
+	logger.Info("dropped")
+	close(out.gate)
+	a.Close()
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[2], `"droppedLogs":1,"msg":"Log records dropped"}`) {
+		t.Errorf("Invalid report on close. Logs: %s", out.String())
+	}
+}
@@ -0,0 +1,261 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Log formats supported by SetFormat and SetDefaultLogFormat.
+const (
+	LogFormatJSON    = "json"
+	LogFormatLogfmt  = "logfmt"
+	LogFormatConsole = "console"
+)
+
+// LogFormatNames is an array with the valid log formats.
+var LogFormatNames = []string{LogFormatJSON, LogFormatLogfmt, LogFormatConsole}
+
+var defaultEncoder Encoder = JSONEncoder{}
+
+// LogEntry is a log record to be encoded.
+// KeyValues are the fields of the logger (see Logger.With) followed by the fields of the log record
+// (e.g. Logger.InfoKV).
+type LogEntry struct {
+	Time          time.Time
+	Level         string
+	Context       interface{}
+	CustomContext interface{}
+	KeyValues     []LogField
+	Message       string
+}
+
+// Encoder writes a log entry, terminated with a new line, into a buffer.
+// Context and CustomContext are structs (or maps) whose fields are marshalled as log fields
+// (see LogEntry.Fields).
+type Encoder interface {
+	Encode(buf *bytes.Buffer, entry *LogEntry)
+}
+
+// LogField is a field of a log context.
+// Value is the JSON encoding of the field value (with the secrets redacted).
+type LogField struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// Fields returns the fields of the logger context, the fields of the custom context and the key/value fields,
+// in the same order as in the JSON format.
+func (e *LogEntry) Fields() []LogField {
+	fields := append(logFields(e.Context), logFields(e.CustomContext)...)
+	return append(fields, e.KeyValues...)
+}
+
+// badLogKey is the key of the values without a valid key in a list of key/value pairs.
+const badLogKey = "!BADKEY"
+
+// logFieldPrefix is the prefix of the keys named as a field of the log format (see reservedLogKeys).
+const logFieldPrefix = "fields."
+
+// reservedLogKeys are the keys written by the logger in every record.
+var reservedLogKeys = map[string]bool{"time": true, "lvl": true, "msg": true}
+
+// appendLogFields appends a list of key/value pairs (e.g. "orderId", id, "attempt", 2) to fields.
+// The reserved keys are prefixed (e.g. "msg" is logged as "fields.msg"), so they do not duplicate the keys of the
+// log record.
+func appendLogFields(fields []LogField, keyValues []interface{}) []LogField {
+	for i := 0; i < len(keyValues); i++ {
+		key, ok := keyValues[i].(string)
+		if !ok || i == len(keyValues)-1 {
+			fields = append(fields, newLogField(badLogKey, keyValues[i]))
+			continue
+		}
+		i++
+		if reservedLogKeys[key] {
+			key = logFieldPrefix + key
+		}
+		fields = append(fields, newLogField(key, keyValues[i]))
+	}
+	return fields
+}
+
+// newLogField marshals the value of a field. Errors are logged with their message and secrets are redacted.
+func newLogField(key string, value interface{}) LogField {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	b, err := MarshalRedacted(value)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprintf("%+v", value))
+	}
+	return LogField{Key: key, Value: b}
+}
+
+// logFields returns the fields of a context in the order they are marshalled.
+func logFields(v interface{}) []LogField {
+	if v == nil {
+		return nil
+	}
+	b, err := MarshalRedacted(v)
+	if err != nil {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+	var fields []LogField
+	for dec.More() {
+		token, err := dec.Token()
+		key, ok := token.(string)
+		if err != nil || !ok {
+			break
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			break
+		}
+		fields = append(fields, LogField{Key: key, Value: value})
+	}
+	return fields
+}
+
+// SetDefaultLogFormat sets the default log format (see LogFormatNames) of the new loggers. This default can be
+// overridden with SetFormat method. An unknown format selects the JSON format.
+func SetDefaultLogFormat(format string) {
+	defaultEncoder = encoderByName(format)
+}
+
+func encoderByName(format string) Encoder {
+	switch strings.ToLower(format) {
+	case LogFormatLogfmt:
+		return LogfmtEncoder{}
+	case LogFormatConsole:
+		return ConsoleEncoder{}
+	}
+	return JSONEncoder{}
+}
+
+// JSONEncoder writes the log entries as JSON documents. This is the default format.
+type JSONEncoder struct{}
+
+// Encode writes the log entry as a JSON document.
+func (JSONEncoder) Encode(buf *bytes.Buffer, entry *LogEntry) {
+	writeDoc(buf, entry.Time, entry.Level, entry.Context, entry.CustomContext, entry.KeyValues, entry.Message)
+}
+
+// LogfmtEncoder writes the log entries in logfmt format (key=value pairs separated by spaces).
+// Nested objects and arrays are written as quoted JSON values.
+type LogfmtEncoder struct{}
+
+// Encode writes the log entry in logfmt format.
+func (LogfmtEncoder) Encode(buf *bytes.Buffer, entry *LogEntry) {
+	buf.WriteString("time=")
+	buf.WriteString(entry.Time.Format(RFC3339Milli))
+	buf.WriteString(" lvl=")
+	buf.WriteString(entry.Level)
+	for _, field := range entry.Fields() {
+		buf.WriteByte(' ')
+		buf.WriteString(field.Key)
+		buf.WriteByte('=')
+		buf.WriteString(logfmtValue(field.Value))
+	}
+	buf.WriteString(" msg=")
+	buf.WriteString(logfmtQuote(entry.Message))
+	buf.WriteByte('\n')
+}
+
+// logfmtValue converts a JSON value into a logfmt value. Strings are unquoted if possible.
+func logfmtValue(value json.RawMessage) string {
+	switch value[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			return logfmtQuote(s)
+		}
+	case '{', '[':
+		return logfmtQuote(string(value))
+	}
+	return string(value)
+}
+
+// logfmtQuote quotes a string if it is empty or it contains spaces, quotes, equal signs or
+// non-printable characters.
+func logfmtQuote(s string) string {
+	needsQuotes := strings.IndexFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r)
+	}) >= 0
+	if s == "" || needsQuotes {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// ANSI escape codes used by ConsoleEncoder.
+const (
+	colorReset = "\x1b[0m"
+	colorFaint = "\x1b[2m"
+)
+
+var levelColors = map[string]string{
+	"DEBUG": "\x1b[90m",
+	"INFO":  "\x1b[32m",
+	"WARN":  "\x1b[33m",
+	"ERROR": "\x1b[31m",
+	"FATAL": "\x1b[1;31m",
+}
+
+// ConsoleEncoder writes the log entries in a human-readable format for local development: time (without date),
+// level, message and the context fields as key=value pairs. The level and the keys are colored unless NoColor
+// is set.
+type ConsoleEncoder struct {
+	NoColor bool
+}
+
+// Encode writes the log entry in a human-readable format.
+func (e ConsoleEncoder) Encode(buf *bytes.Buffer, entry *LogEntry) {
+	e.writeColored(buf, colorFaint, entry.Time.Format("15:04:05.000"))
+	buf.WriteByte(' ')
+	e.writeColored(buf, levelColors[entry.Level], entry.Level)
+	if padding := 6 - len(entry.Level); padding > 0 {
+		buf.WriteString(strings.Repeat(" ", padding))
+	}
+	buf.WriteString(entry.Message)
+	for _, field := range entry.Fields() {
+		buf.WriteByte(' ')
+		e.writeColored(buf, colorFaint, field.Key+"=")
+		buf.WriteString(logfmtValue(field.Value))
+	}
+	buf.WriteByte('\n')
+}
+
+func (e ConsoleEncoder) writeColored(buf *bytes.Buffer, color, s string) {
+	if e.NoColor || color == "" {
+		buf.WriteString(s)
+		return
+	}
+	buf.WriteString(color)
+	buf.WriteString(s)
+	buf.WriteString(colorReset)
+}
@@ -0,0 +1,113 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type encoderContext struct {
+	Feature  int               `json:"feat,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Password string            `json:"password,omitempty" secret:"true"`
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	now := time.Date(2018, 5, 10, 8, 1, 51, 335000000, time.UTC)
+	tests := []struct {
+		entry    LogEntry
+		expected string
+	}{
+		{
+			LogEntry{Time: now, Level: "INFO", Message: "Test"},
+			`time=2018-05-10T08:01:51.335Z lvl=INFO msg=Test`,
+		},
+		{
+			LogEntry{Time: now, Level: "ERROR", Context: ctxtA, CustomContext: ctxtB, Message: "This is a test"},
+			`time=2018-05-10T08:01:51.335Z lvl=ERROR trans=txid op=op1 method=GET path=/users msg="This is a test"`,
+		},
+		{
+			LogEntry{Time: now, Level: "WARN", CustomContext: RespLogContext{Status: 200, Latency: 4}, Message: `a "b"=c`},
+			`time=2018-05-10T08:01:51.335Z lvl=WARN status=200 latency=4 msg="a \"b\"=c"`,
+		},
+		{
+			LogEntry{Time: now, Level: "DEBUG", CustomContext: &encoderContext{Feature: 3, Labels: map[string]string{"a": "b"}, Password: "s3cr3t"}, Message: ""},
+			`time=2018-05-10T08:01:51.335Z lvl=DEBUG feat=3 labels="{\"a\":\"b\"}" password=****** msg=""`,
+		},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		LogfmtEncoder{}.Encode(&buf, &test.entry)
+		if buf.String() != test.expected+"\n" {
+			t.Errorf("Invalid logfmt entry. Actual: %s. Expected: %s", buf.String(), test.expected)
+		}
+	}
+}
+
+func TestConsoleEncoder(t *testing.T) {
+	now := time.Date(2018, 5, 10, 8, 1, 51, 335000000, time.UTC)
+	entry := LogEntry{Time: now, Level: "INFO", Context: ctxtA, CustomContext: ctxtB, Message: "This is a test"}
+	var buf bytes.Buffer
+	ConsoleEncoder{NoColor: true}.Encode(&buf, &entry)
+	expected := "08:01:51.335 INFO  This is a test trans=txid op=op1 method=GET path=/users\n"
+	if buf.String() != expected {
+		t.Errorf("Invalid console entry. Actual: %q. Expected: %q", buf.String(), expected)
+	}
+	buf.Reset()
+	ConsoleEncoder{}.Encode(&buf, &entry)
+	expected = "\x1b[2m08:01:51.335\x1b[0m \x1b[32mINFO\x1b[0m  This is a test \x1b[2mtrans=\x1b[0mtxid " +
+		"\x1b[2mop=\x1b[0mop1 \x1b[2mmethod=\x1b[0mGET \x1b[2mpath=\x1b[0m/users\n"
+	if buf.String() != expected {
+		t.Errorf("Invalid colored console entry. Actual: %q. Expected: %q", buf.String(), expected)
+	}
+}
+
+func TestLoggerFormat(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected Encoder
+	}{
+		{"", JSONEncoder{}},
+		{"invalid", JSONEncoder{}},
+		{"json", JSONEncoder{}},
+		{"logfmt", LogfmtEncoder{}},
+		{"Console", ConsoleEncoder{}},
+	}
+	for _, test := range tests {
+		logger := NewLogger()
+		logger.SetFormat(test.format)
+		if encoder := logger.GetEncoder(); encoder != test.expected {
+			t.Errorf("Invalid encoder for %s. Actual: %T. Expected: %T", test.format, encoder, test.expected)
+		}
+	}
+	SetDefaultLogFormat("logfmt")
+	logger := NewLogger()
+	SetDefaultLogFormat("json")
+	var buf bytes.Buffer
+	logger.SetWriter(&buf)
+	logger.InfoC(ctxtB, "Logging with %s", "logfmt")
+	expected := " lvl=INFO method=GET path=/users msg=\"Logging with logfmt\"\n"
+	if actual := buf.String(); !bytes.HasSuffix([]byte(actual), []byte(expected)) {
+		t.Errorf("Invalid log entry. Actual: %s. Expected suffix: %s", actual, expected)
+	}
+	if encoder := NewLogger().GetEncoder(); encoder != (JSONEncoder{}) {
+		t.Errorf("Invalid default encoder. Actual: %T", encoder)
+	}
+}
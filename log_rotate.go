@@ -0,0 +1,318 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeLayout is the layout of the time in the name of the rotated files.
+const rotatedTimeLayout = "2006-01-02T15-04-05.000"
+
+// RotatingFile is an io.Writer that writes the logs to a file and rotates it when it exceeds a maximum size or
+// when the day changes. The rotated files are renamed with the time of the rotation (e.g. service.log is rotated
+// to service-2018-05-10T08-01-51.335.log, or service-2018-05-10T08-01-51.335-1.log if the file was already rotated
+// in the same millisecond), optionally compressed with gzip, and removed when there are too many or they are too old.
+// It is safe to share a RotatingFile between several loggers (e.g. with SetDefaultLogWriter, the global logger
+// and the loggers created for every request by WithLogContext).
+type RotatingFile struct {
+	file       string
+	maxSize    ByteSize
+	daily      bool
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+	mutex      sync.Mutex
+	out        *os.File
+	size       int64
+	day        string
+	stop       chan struct{}
+	millMutex  sync.Mutex
+	millGroup  sync.WaitGroup
+	now        func() time.Time
+}
+
+// NewRotatingFile creates a RotatingFile that writes to file. The file (and its directory) is created with the
+// first write. By default, the file is never rotated.
+func NewRotatingFile(file string) *RotatingFile {
+	return &RotatingFile{file: file, now: time.Now}
+}
+
+// SetMaxSize sets the maximum size of the file before it is rotated (0 disables the rotation by size).
+func (r *RotatingFile) SetMaxSize(maxSize ByteSize) {
+	r.maxSize = maxSize
+}
+
+// SetDaily enables the rotation of the file when the day (in local time) changes.
+func (r *RotatingFile) SetDaily(daily bool) {
+	r.daily = daily
+}
+
+// SetMaxBackups sets the maximum number of rotated files to keep (0 keeps all of them).
+func (r *RotatingFile) SetMaxBackups(maxBackups int) {
+	r.maxBackups = maxBackups
+}
+
+// SetMaxAge sets the maximum age of the rotated files to keep (0 keeps all of them).
+func (r *RotatingFile) SetMaxAge(maxAge time.Duration) {
+	r.maxAge = maxAge
+}
+
+// SetCompress enables the compression of the rotated files with gzip (adding the .gz extension).
+func (r *RotatingFile) SetCompress(compress bool) {
+	r.compress = compress
+}
+
+// Write writes p to the file, rotating it before if required.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	now := r.now()
+	if r.out == nil {
+		if err := r.open(now); err != nil {
+			return 0, err
+		}
+	}
+	exceedsSize := r.maxSize > 0 && r.size+int64(len(p)) > int64(r.maxSize)
+	changedDay := r.daily && r.day != now.Format("2006-01-02")
+	if r.size > 0 && (exceedsSize || changedDay) {
+		if err := r.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.out.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file.
+func (r *RotatingFile) Rotate() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.rotate(r.now())
+}
+
+// Reopen closes and opens the file again. It is required when the file is rotated by another tool
+// (e.g. logrotate). See ReopenOnSignal.
+func (r *RotatingFile) Reopen() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.closeFile()
+	return r.open(r.now())
+}
+
+// ReopenOnSignal reopens the file when the process receives the signal sig (e.g. SIGUSR1 sent by logrotate after
+// rotating the file) until the RotatingFile is closed. Note that ConfigWatcher also reloads the configuration
+// on SIGHUP, so a different signal is recommended if both are used (otherwise, both actions run on SIGHUP).
+func (r *RotatingFile) ReopenOnSignal(sig os.Signal) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.stop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	r.stop = stop
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sig)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-signals:
+				r.Reopen()
+			}
+		}
+	}()
+}
+
+// Close closes the file. It waits until the rotated files are compressed and removed.
+func (r *RotatingFile) Close() error {
+	r.mutex.Lock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	err := r.closeFile()
+	r.mutex.Unlock()
+	r.millGroup.Wait()
+	return err
+}
+
+// open opens (or creates) the file to append the logs. The day of the file is the day of its last
+// modification if it is not empty.
+func (r *RotatingFile) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(r.file), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(r.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := out.Stat()
+	if err != nil {
+		out.Close()
+		return err
+	}
+	r.out = out
+	r.size = info.Size()
+	if r.size > 0 {
+		now = info.ModTime()
+	}
+	r.day = now.Format("2006-01-02")
+	return nil
+}
+
+func (r *RotatingFile) closeFile() error {
+	if r.out == nil {
+		return nil
+	}
+	err := r.out.Close()
+	r.out = nil
+	return err
+}
+
+// rotate renames the file, opens a new one and compresses and removes the rotated files in background.
+func (r *RotatingFile) rotate(now time.Time) error {
+	r.closeFile()
+	rotated := r.rotatedFileName(now)
+	if err := os.Rename(r.file, rotated); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := r.open(now); err != nil {
+		return err
+	}
+	r.millGroup.Add(1)
+	go func() {
+		defer r.millGroup.Done()
+		r.millMutex.Lock()
+		defer r.millMutex.Unlock()
+		if r.compress {
+			compressFile(rotated)
+		}
+		r.removeRotated(now)
+	}()
+	return nil
+}
+
+// rotatedFileName returns the name of the file rotated at now. A counter is added if there is already a file
+// rotated at the same time (e.g. service-2018-05-10T08-01-51.335-1.log).
+func (r *RotatingFile) rotatedFileName(now time.Time) string {
+	ext := filepath.Ext(r.file)
+	base := strings.TrimSuffix(r.file, ext) + "-" + now.Format(rotatedTimeLayout)
+	rotated := base + ext
+	for i := 1; fileExists(rotated) || fileExists(rotated+".gz"); i++ {
+		rotated = base + "-" + strconv.Itoa(i) + ext
+	}
+	return rotated
+}
+
+func fileExists(file string) bool {
+	_, err := os.Lstat(file)
+	return err == nil
+}
+
+// removeRotated removes the rotated files exceeding the maximum number of backups or the maximum age.
+func (r *RotatingFile) removeRotated(now time.Time) {
+	if r.maxBackups <= 0 && r.maxAge <= 0 {
+		return
+	}
+	ext := filepath.Ext(r.file)
+	prefix := filepath.Base(strings.TrimSuffix(r.file, ext)) + "-"
+	files, err := ioutil.ReadDir(filepath.Dir(r.file))
+	if err != nil {
+		return
+	}
+	type rotatedFile struct {
+		name    string
+		time    time.Time
+		counter int
+	}
+	var rotated []rotatedFile
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		timestamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)[len(prefix):]
+		counter := 0
+		if len(timestamp) > len(rotatedTimeLayout) {
+			if timestamp[len(rotatedTimeLayout)] != '-' {
+				continue
+			}
+			if counter, err = strconv.Atoi(timestamp[len(rotatedTimeLayout)+1:]); err != nil {
+				continue
+			}
+			timestamp = timestamp[:len(rotatedTimeLayout)]
+		}
+		t, err := time.ParseInLocation(rotatedTimeLayout, timestamp, time.Local)
+		if err != nil {
+			continue
+		}
+		rotated = append(rotated, rotatedFile{name: name, time: t, counter: counter})
+	}
+	sort.Slice(rotated, func(i, j int) bool {
+		if rotated[i].time.Equal(rotated[j].time) {
+			return rotated[i].counter > rotated[j].counter
+		}
+		return rotated[i].time.After(rotated[j].time)
+	})
+	for i, file := range rotated {
+		if (r.maxBackups > 0 && i >= r.maxBackups) || (r.maxAge > 0 && now.Sub(file.time) > r.maxAge) {
+			os.Remove(filepath.Join(filepath.Dir(r.file), file.name))
+		}
+	}
+}
+
+// compressFile compresses a file with gzip (adding the .gz extension) and removes the original file.
+// If the compression fails, the original file is kept.
+func compressFile(file string) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(file+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file + ".gz")
+		return err
+	}
+	return os.Remove(file)
+}
@@ -0,0 +1,189 @@
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestRotatingFile(t *testing.T) (*RotatingFile, string, *time.Time) {
+	dir, err := ioutil.TempDir("", "govice")
+	if err != nil {
+		t.Fatalf("Error creating temporary directory. %s", err)
+	}
+	now := time.Date(2018, 5, 10, 8, 1, 51, 335000000, time.Local)
+	r := NewRotatingFile(filepath.Join(dir, "logs", "service.log"))
+	r.now = func() time.Time { return now }
+	return r, dir, &now
+}
+
+func listRotatedFiles(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Error reading log directory. %s", err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	r, dir, now := newTestRotatingFile(t)
+	defer os.RemoveAll(dir)
+	r.SetMaxSize(10 * Byte)
+	r.SetMaxBackups(2)
+	for _, line := range []string{"line1\n", "line2\n", "line3\n", "line4\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Error writing log. %s", err)
+		}
+		*now = now.Add(time.Second)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Error closing file. %s", err)
+	}
+	expected := []string{"service-2018-05-10T08-01-53.335.log", "service-2018-05-10T08-01-54.335.log", "service.log"}
+	actual := listRotatedFiles(t, filepath.Join(dir, "logs"))
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("Invalid log files. Actual: %v. Expected: %v", actual, expected)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "logs", "service.log")); string(data) != "line4\n" {
+		t.Errorf("Invalid log file. Actual: %q", data)
+	}
+}
+
+func TestRotatingFileSameTime(t *testing.T) {
+	r, dir, _ := newTestRotatingFile(t)
+	defer os.RemoveAll(dir)
+	r.SetMaxSize(10 * Byte)
+	r.SetMaxBackups(2)
+	for _, line := range []string{"line1\n", "line2\n", "line3\n", "line4\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Error writing log. %s", err)
+		}
+	}
+	r.Close()
+	expected := []string{"service-2018-05-10T08-01-51.335-1.log", "service-2018-05-10T08-01-51.335-2.log", "service.log"}
+	actual := listRotatedFiles(t, filepath.Join(dir, "logs"))
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("Invalid log files. Actual: %v. Expected: %v", actual, expected)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "logs", expected[1])); string(data) != "line3\n" {
+		t.Errorf("Invalid rotated file. Actual: %q", data)
+	}
+}
+
+func TestRotatingFileDailyCompressed(t *testing.T) {
+	r, dir, now := newTestRotatingFile(t)
+	defer os.RemoveAll(dir)
+	r.SetDaily(true)
+	r.SetCompress(true)
+	r.SetMaxAge(36 * time.Hour)
+	for i := 0; i < 3; i++ {
+		r.Write([]byte("first\n"))
+		r.Write([]byte("second\n"))
+		*now = now.Add(24 * time.Hour)
+	}
+	r.Close()
+	expected := []string{"service-2018-05-11T08-01-51.335.log.gz", "service-2018-05-12T08-01-51.335.log.gz", "service.log"}
+	actual := listRotatedFiles(t, filepath.Join(dir, "logs"))
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Invalid log files. Actual: %v. Expected: %v", actual, expected)
+	}
+	f, err := os.Open(filepath.Join(dir, "logs", expected[1]))
+	if err != nil {
+		t.Fatalf("Error opening rotated file. %s", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Error reading compressed file. %s", err)
+	}
+	if data, _ := ioutil.ReadAll(gz); string(data) != "first\nsecond\n" {
+		t.Errorf("Invalid rotated file. Actual: %q", data)
+	}
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	r, dir, _ := newTestRotatingFile(t)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "logs", "service.log")
+	r.Write([]byte("before\n"))
+	// Rotation by logrotate
+	if err := os.Rename(file, file+".1"); err != nil {
+		t.Fatalf("Error renaming log file. %s", err)
+	}
+	if err := r.Reopen(); err != nil {
+		t.Fatalf("Error reopening log file. %s", err)
+	}
+	r.Write([]byte("after\n"))
+	r.Close()
+	if data, _ := ioutil.ReadFile(file); string(data) != "after\n" {
+		t.Errorf("Invalid log file. Actual: %q", data)
+	}
+	if data, _ := ioutil.ReadFile(file + ".1"); string(data) != "before\n" {
+		t.Errorf("Invalid rotated file. Actual: %q", data)
+	}
+}
+
+func TestRotatingFileSharedByLoggers(t *testing.T) {
+	r, dir, _ := newTestRotatingFile(t)
+	defer os.RemoveAll(dir)
+	r.SetMaxSize(Megabyte)
+	SetDefaultLogWriter(r)
+	defer SetDefaultLogWriter(os.Stdout)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger := NewLogger()
+			for j := 0; j < 20; j++ {
+				logger.Info("Concurrent log record")
+			}
+		}()
+	}
+	wg.Wait()
+	r.Close()
+	lines := 0
+	for _, name := range listRotatedFiles(t, filepath.Join(dir, "logs")) {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "logs", name))
+		if err != nil {
+			t.Fatalf("Error reading log file. %s", err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			if !strings.HasSuffix(line, `"msg":"Concurrent log record"}`) {
+				t.Errorf("Invalid log record: %s", line)
+			}
+			lines++
+		}
+	}
+	if lines != 200 {
+		t.Errorf("Invalid number of log records. Actual: %d. Expected: 200", lines)
+	}
+}
@@ -0,0 +1,211 @@
+//go:build go1.21
+
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+// SlogLevelFatal is the slog level mapped to the FATAL level of the govice logger.
+// The slog levels are mapped to the closest lower govice level (e.g. slog.LevelWarn+1 is logged as WARN).
+const SlogLevelFatal = slog.LevelError + 4
+
+// SlogHandler is a slog.Handler that writes the records with a govice Logger, so the code using the log/slog API
+// keeps the govice log format. The attributes and groups are logged as fields after the log context. The
+// attributes named as the fields of the log format (time, lvl and msg) are prefixed with "fields." (e.g.
+// fields.msg) to avoid duplicated keys. If the
+// context passed to the slog logger is a request context with a logger (see LoggerContextKey), this logger is used
+// instead (e.g. to log the transactionID and correlator of the request).
+type SlogHandler struct {
+	logger *Logger
+	fields []LogField
+	groups []slogGroup
+}
+
+// slogGroup is a group opened with WithGroup and the fields added to it with WithAttrs.
+type slogGroup struct {
+	name   string
+	fields []LogField
+}
+
+// NewSlogHandler creates a SlogHandler that writes the records with logger.
+func NewSlogHandler(logger *Logger) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+// NewSlogLogger returns a slog logger that writes the records with logger.
+func NewSlogLogger(logger *Logger) *slog.Logger {
+	return slog.New(NewSlogHandler(logger))
+}
+
+// slogLevel maps a slog level to a govice log level.
+func slogLevel(l slog.Level) level {
+	switch {
+	case l < slog.LevelInfo:
+		return debugLevel
+	case l < slog.LevelWarn:
+		return infoLevel
+	case l < slog.LevelError:
+		return warnLevel
+	case l < SlogLevelFatal:
+		return errorLevel
+	}
+	return fatalLevel
+}
+
+// getLogger returns the logger of the request context or, otherwise, the logger of the handler.
+func (h *SlogHandler) getLogger(ctx context.Context) *Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(LoggerContextKey).(*Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	return h.logger
+}
+
+// Enabled reports whether the level is enabled by the logger.
+func (h *SlogHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return slogLevel(l) >= h.getLogger(ctx).shared().logLevel
+}
+
+// Handle writes the record with the logger.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var fields []LogField
+	r.Attrs(func(attr slog.Attr) bool {
+		fields = appendSlogAttr(fields, attr)
+		return true
+	})
+	// Nest the fields in the open groups, from the innermost
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		group := h.groups[i]
+		groupFields := append(append([]LogField{}, group.fields...), fields...)
+		fields = nil
+		if len(groupFields) > 0 {
+			fields = []LogField{{Key: group.name, Value: marshalLogFields(groupFields)}}
+		}
+	}
+	logger := h.getLogger(ctx)
+	fields = append(append(append([]LogField{}, logger.fields...), renameSlogFields(h.fields)...), renameSlogFields(fields)...)
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	logger.write(t, slogLevel(r.Level), nil, fields, r.Message)
+	return nil
+}
+
+// WithAttrs returns a handler that adds the attributes to every record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := h.clone()
+	if len(c.groups) == 0 {
+		for _, attr := range attrs {
+			c.fields = appendSlogAttr(c.fields, attr)
+		}
+		return c
+	}
+	group := &c.groups[len(c.groups)-1]
+	group.fields = append([]LogField{}, group.fields...)
+	for _, attr := range attrs {
+		group.fields = appendSlogAttr(group.fields, attr)
+	}
+	return c
+}
+
+// WithGroup returns a handler that nests the attributes added afterwards in a group.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := h.clone()
+	c.groups = append(c.groups, slogGroup{name: name})
+	return c
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	return &SlogHandler{
+		logger: h.logger,
+		fields: append([]LogField{}, h.fields...),
+		groups: append([]slogGroup{}, h.groups...),
+	}
+}
+
+// appendSlogAttr appends a slog attribute to fields. Groups are nested objects (inlined if the key is empty),
+// and empty attributes and groups are ignored.
+func appendSlogAttr(fields []LogField, attr slog.Attr) []LogField {
+	value := attr.Value.Resolve()
+	if attr.Key == "" && value.Kind() != slog.KindGroup {
+		return fields
+	}
+	switch value.Kind() {
+	case slog.KindGroup:
+		var groupFields []LogField
+		for _, groupAttr := range value.Group() {
+			groupFields = appendSlogAttr(groupFields, groupAttr)
+		}
+		if len(groupFields) == 0 {
+			return fields
+		}
+		if attr.Key == "" {
+			return append(fields, groupFields...)
+		}
+		return append(fields, LogField{Key: attr.Key, Value: marshalLogFields(groupFields)})
+	case slog.KindDuration:
+		return append(fields, newLogField(attr.Key, value.Duration().String()))
+	case slog.KindTime:
+		return append(fields, newLogField(attr.Key, value.Time().Format(RFC3339Milli)))
+	}
+	return append(fields, newLogField(attr.Key, value.Any()))
+}
+
+// renameSlogFields returns the fields with the reserved keys prefixed (see logFieldPrefix).
+func renameSlogFields(fields []LogField) []LogField {
+	var renamed []LogField
+	for i, field := range fields {
+		if !reservedLogKeys[field.Key] {
+			continue
+		}
+		if renamed == nil {
+			renamed = append([]LogField{}, fields...)
+		}
+		renamed[i].Key = logFieldPrefix + field.Key
+	}
+	if renamed == nil {
+		return fields
+	}
+	return renamed
+}
+
+// marshalLogFields marshals a list of fields as a JSON object (keeping the order of the fields).
+func marshalLogFields(fields []LogField) json.RawMessage {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeRawField(&buf, field.Key, field.Value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
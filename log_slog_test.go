@@ -0,0 +1,122 @@
+//go:build go1.21
+
+/**
+ * @license
+ * Copyright 2017 Telefónica Investigación y Desarrollo, S.A.U
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package govice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlogLevel(t *testing.T) {
+	tests := []struct {
+		slogLevel slog.Level
+		expected  level
+	}{
+		{slog.LevelDebug - 4, debugLevel},
+		{slog.LevelDebug, debugLevel},
+		{slog.LevelInfo, infoLevel},
+		{slog.LevelInfo + 2, infoLevel},
+		{slog.LevelWarn, warnLevel},
+		{slog.LevelError, errorLevel},
+		{SlogLevelFatal, fatalLevel},
+		{SlogLevelFatal + 4, fatalLevel},
+	}
+	for _, test := range tests {
+		if actual := slogLevel(test.slogLevel); actual != test.expected {
+			t.Errorf("Invalid level for %s. Actual: %d. Expected: %d", test.slogLevel, actual, test.expected)
+		}
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{out: &buf, logLevel: infoLevel}
+	logger.SetLogContext(ctxtA)
+	slogger := NewSlogLogger(logger).With("svc", "orders")
+
+	slogger.Debug("Filtered")
+	slogger.Info("Order created", "orderId", "o1", "latency", 2*time.Millisecond)
+	slogger.WithGroup("req").With("method", "GET").Warn("Slow request", slog.Group("resp", "status", 200), "empty", slog.GroupValue())
+	slogger.Error("Order failed", "err", errors.New("timeout"), slog.Group("", "attempt", 2))
+	slogger.Log(context.Background(), SlogLevelFatal, "Fatal %s")
+
+	expected := []string{
+		`"lvl":"INFO","trans":"txid","op":"op1","svc":"orders","orderId":"o1","latency":"2ms","msg":"Order created"}`,
+		`"lvl":"WARN","trans":"txid","op":"op1","svc":"orders","req":{"method":"GET","resp":{"status":200}},"msg":"Slow request"}`,
+		`"lvl":"ERROR","trans":"txid","op":"op1","svc":"orders","err":"timeout","attempt":2,"msg":"Order failed"}`,
+		`"lvl":"FATAL","trans":"txid","op":"op1","svc":"orders","msg":"Fatal %s"}`,
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Invalid number of log records. Actual: %d. Expected: %d. Logs: %s", len(lines), len(expected), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, expected[i]) {
+			t.Errorf("Invalid log record. Actual: %s. Expected suffix: %s", line, expected[i])
+		}
+	}
+}
+
+func TestSlogHandlerReservedKeys(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{out: &buf, logLevel: infoLevel}
+	slogger := NewSlogLogger(logger).With("time", "t1")
+	slogger.Info("Message", "msg", "m1", "lvl", "l1", slog.Group("req", "msg", "nested"))
+	expected := `,"lvl":"INFO","fields.time":"t1","fields.msg":"m1","fields.lvl":"l1","req":{"msg":"nested"},"msg":"Message"}` + "\n"
+	if actual := extractFirstField(buf.String()); actual != expected {
+		t.Errorf("Invalid log. Actual: %s. Expected: %s", actual, expected)
+	}
+}
+
+func TestSlogHandlerRequestLogger(t *testing.T) {
+	var buf, reqBuf bytes.Buffer
+	logger := &Logger{out: &buf, logLevel: infoLevel}
+	slogger := NewSlogLogger(logger).WithGroup("order").With("id", "o1")
+
+	r := httptest.NewRequest("GET", "/orders", nil)
+	var ctx context.Context
+	handler := WithLogContext(&LogContext{Operation: "getOrder"})(func(w http.ResponseWriter, r *http.Request) {
+		GetLogger(r).SetWriter(&reqBuf)
+		GetLogger(r).SetLevel("DEBUG")
+		ctx = r.Context()
+		slogger.DebugContext(ctx, "Order found")
+	})
+	handler(httptest.NewRecorder(), r)
+
+	if buf.String() != "" {
+		t.Errorf("Unexpected log record with the handler logger: %s", buf.String())
+	}
+	reqLogger := GetLogger(r.WithContext(ctx))
+	trans := reqLogger.GetLogContext().(*LogContext).TransactionID
+	expected := `"lvl":"DEBUG","trans":"` + trans + `","corr":"` + trans + `","op":"getOrder","order":{"id":"o1"},"msg":"Order found"}` + "\n"
+	if !strings.HasSuffix(reqBuf.String(), expected) {
+		t.Errorf("Invalid log record. Actual: %s. Expected suffix: %s", reqBuf.String(), expected)
+	}
+	if slogger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Errorf("Expected DEBUG level disabled without request logger")
+	}
+}
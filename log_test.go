@@ -155,7 +155,7 @@ func TestWriteDoc(t *testing.T) {
 	}
 	for _, test := range tests {
 		var buf bytes.Buffer
-		writeDoc(&buf, now, test.logLevel, test.ctxtA, test.ctxtB, test.message)
+		writeDoc(&buf, now, test.logLevel, test.ctxtA, test.ctxtB, nil, test.message)
 		expected := test.expected + "\n"
 		if buf.String() != expected {
 			t.Errorf("Invalid writeDoc. Actual: %s. Expected: %s", buf.String(), expected)
@@ -392,7 +392,7 @@ func TestLogLevelMismatch(t *testing.T) {
 	for _, test := range tests {
 		var buf bytes.Buffer
 		logger := &Logger{out: &buf, logLevel: test.loggerLvl}
-		logger.log(test.recordLvl, nil, test.msg, test.args...)
+		logger.log(test.recordLvl, nil, nil, test.msg, test.args...)
 		if buf.String() != "" {
 			t.Errorf("Invalid log. Expected no entry but received: %s", buf.String())
 		}
@@ -422,3 +422,72 @@ func TestStdLoggerC(t *testing.T) {
 		t.Errorf("Invalid std log. Actual: %s. Expected : %s", buf.String(), expected)
 	}
 }
+
+func TestLoggerWith(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{out: &buf, logLevel: infoLevel}
+	logger.SetLogContext(ctxtA)
+	child := logger.With("orderId", "o1", "attempt", 2)
+	grandchild := child.With("retry", true)
+
+	child.InfoC(ctxtB, "Processing order %s", "o1")
+	grandchild.WarnKV("Order failed", "err", fmt.Errorf("timeout"), "code", 504)
+	logger.Info("Parent")
+	child.DebugKV("Filtered")
+	logger.SetLevel("DEBUG")
+	if level := child.GetLevel(); level != "DEBUG" {
+		t.Errorf("Invalid child level. Actual: %s. Expected: DEBUG", level)
+	}
+	child.ErrorKV("Unpaired", 5, "key")
+
+	expected := []string{
+		`"lvl":"INFO","trans":"txid","op":"op1","method":"GET","path":"/users","orderId":"o1","attempt":2,"msg":"Processing order o1"}`,
+		`"lvl":"WARN","trans":"txid","op":"op1","orderId":"o1","attempt":2,"retry":true,"err":"timeout","code":504,"msg":"Order failed"}`,
+		`"lvl":"INFO","trans":"txid","op":"op1","msg":"Parent"}`,
+		`"lvl":"ERROR","trans":"txid","op":"op1","orderId":"o1","attempt":2,"!BADKEY":5,"!BADKEY":"key","msg":"Unpaired"}`,
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Invalid number of log records. Actual: %d. Expected: %d. Logs: %s", len(lines), len(expected), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, expected[i]) {
+			t.Errorf("Invalid log record. Actual: %s. Expected suffix: %s", line, expected[i])
+		}
+	}
+}
+
+func TestLoggerWithReservedKeys(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{out: &buf, logLevel: infoLevel}
+	logger.With("msg", "x", "lvl", "y").Info("hello")
+	logger.InfoKV("hello", "time", "z")
+	expected := []string{
+		`,"lvl":"INFO","fields.msg":"x","fields.lvl":"y","msg":"hello"}`,
+		`,"lvl":"INFO","fields.time":"z","msg":"hello"}`,
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Invalid number of log records. Actual: %d. Expected: %d. Logs: %s", len(lines), len(expected), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, expected[i]) || strings.Count(line, `"time":`) != 1 {
+			t.Errorf("Invalid log record. Actual: %s. Expected suffix: %s", line, expected[i])
+		}
+	}
+}
+
+func TestLoggerWithSharedWriter(t *testing.T) {
+	logger := NewLogger()
+	child := logger.With("k", "v")
+	var buf bytes.Buffer
+	logger.SetWriter(&buf)
+	logger.SetFormat("logfmt")
+	child.Info("Shared writer")
+	if child.GetWriter() != &buf {
+		t.Errorf("Expected the writer of the parent logger")
+	}
+	if actual := buf.String(); !strings.HasSuffix(actual, ` lvl=INFO k=v msg="Shared writer"`+"\n") {
+		t.Errorf("Invalid log record. Actual: %s", actual)
+	}
+}
@@ -67,8 +67,13 @@ func (v *Validator) LoadSchemas(schemasDir string) error {
 }
 
 // ValidateConfig to validate the configuration against config.json schema.
+// The values decoded as text by the ConfigLoader (e.g. time.Duration) are validated with their text syntax.
 func (v *Validator) ValidateConfig(schemaName string, config interface{}) error {
-	if err := v.ValidateObject(schemaName, config); err != nil {
+	data, err := marshalConfig(config)
+	if err != nil {
+		return fmt.Errorf("Invalid configuration according to JSON schema: %s", err)
+	}
+	if err := v.ValidateObject(schemaName, json.RawMessage(data)); err != nil {
 		return fmt.Errorf("Invalid configuration according to JSON schema: %s", err)
 	}
 	return nil
@@ -134,13 +139,7 @@ func (v *Validator) ValidateBytes(schemaName string, data []byte, o interface{})
 
 // validate validates a document (documentLoader) with a schema.
 func (v *Validator) validate(schemaName string, documentLoader gojsonschema.JSONLoader) error {
-	// Retrieve the JSON schema
-	schema := v.schemas[schemaName]
-	if schema == nil {
-		return fmt.Errorf("schema %s not found", schemaName)
-	}
-	// Validate document against JSON schema
-	result, err := schema.Validate(documentLoader)
+	result, err := v.validateResult(schemaName, documentLoader)
 	if err != nil {
 		return err
 	}
@@ -153,6 +152,17 @@ func (v *Validator) validate(schemaName string, documentLoader gojsonschema.JSON
 	return nil
 }
 
+// validateResult validates a document (documentLoader) with a schema and returns the result with all the errors.
+func (v *Validator) validateResult(schemaName string, documentLoader gojsonschema.JSONLoader) (*gojsonschema.Result, error) {
+	// Retrieve the JSON schema
+	schema := v.schemas[schemaName]
+	if schema == nil {
+		return nil, fmt.Errorf("schema %s not found", schemaName)
+	}
+	// Validate document against JSON schema
+	return schema.Validate(documentLoader)
+}
+
 // getAbsolutePath returns an absolute path. If relpath is absolute, it returns the same value. If relpath is relative, it
 // returns an absolute path relative to current working directory.
 func getAbsolutePath(relpath string) (string, error) {